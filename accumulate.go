@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/reconquest/karma-go"
+)
+
+// AccumulatedScores maps absolute paths of files to their scores accumulated
+// over previous runs.
+type AccumulatedScores map[string]float64
+
+// parseAccumDecay parses --accum-decay value, which is the weight of
+// previously accumulated score, zero means that scores are summed.
+func parseAccumDecay(args map[string]interface{}) (float64, error) {
+	value, ok := args["--accum-decay"].(string)
+	if !ok {
+		return 0, nil
+	}
+
+	decay, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, karma.Format(
+			err,
+			"invalid --accum-decay value: %s", value,
+		)
+	}
+
+	if decay <= 0 || decay >= 1 {
+		return 0, karma.Format(
+			nil,
+			"--accum-decay should be between 0 and 1: %s", value,
+		)
+	}
+
+	return decay, nil
+}
+
+// loadAccumulatedScores reads scores accumulated by previous runs, missing
+// file means that there were no runs yet.
+func loadAccumulatedScores(path string) (AccumulatedScores, error) {
+	scores := AccumulatedScores{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return scores, nil
+		}
+
+		return nil, karma.Format(
+			err,
+			"unable to read %s", path,
+		)
+	}
+
+	err = json.Unmarshal(data, &scores)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to decode %s", path,
+		)
+	}
+
+	return scores, nil
+}
+
+// markAccumulatedScores sets for every file score accumulated by previous
+// runs.
+func markAccumulatedScores(files []*File, scores AccumulatedScores) {
+	for _, file := range files {
+		path, err := filepath.Abs(file.Path)
+		if err != nil {
+			continue
+		}
+
+		file.accumulatedScore = scores[path]
+	}
+}
+
+// saveAccumulatedScores adds scores of given files to accumulated ones, with
+// non-zero decay exponential moving average is kept instead of the sum;
+// scores of files not found during this run are kept as is.
+func saveAccumulatedScores(
+	path string,
+	scores AccumulatedScores,
+	files []*File,
+	decay float64,
+) error {
+	for _, file := range files {
+		key, err := filepath.Abs(file.Path)
+		if err != nil {
+			continue
+		}
+
+		previous, ok := scores[key]
+
+		switch {
+		case decay == 0:
+			scores[key] = previous + float64(file.Score)
+		case !ok:
+			scores[key] = float64(file.Score)
+		default:
+			scores[key] = decay*previous + (1-decay)*float64(file.Score)
+		}
+	}
+
+	data, err := json.Marshal(scores)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to encode accumulated scores",
+		)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to write %s", path,
+		)
+	}
+
+	return nil
+}
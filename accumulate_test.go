@@ -0,0 +1,104 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAccumulatedScores(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+	})
+
+	dir := t.TempDir()
+	scores := filepath.Join(dir, "scores.json")
+
+	first := filepath.Join(dir, "first.json")
+	writeFile(t, first, `{
+    "ignore_dirs": [".git"],
+    "rules": [{"prefix": "b", "score": 20}]
+}`)
+
+	second := filepath.Join(dir, "second.json")
+	writeFile(t, second, `{
+    "ignore_dirs": [".git"],
+    "rules": [
+        {"prefix": "a", "score": 5},
+        {"accumulated_score": ">10", "score": 100}
+    ]
+}`)
+
+	stdout, _, err := runProls(t, "-c", second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "b.go\na.go\n" {
+		t.Fatalf("unexpected output without accumulated scores: %q", stdout)
+	}
+
+	_, _, err = runProls(t, "-c", first, "--append-scores-to", scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err = runProls(t, "-c", second, "--append-scores-to", scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "a.go\nb.go\n" {
+		t.Errorf("accumulated score should move b.go up, got %q", stdout)
+	}
+
+	accumulated, err := loadAccumulatedScores(scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, score := range map[string]float64{"a.go": 5, "b.go": 120} {
+		key, err := filepath.Abs(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if accumulated[key] != score {
+			t.Errorf("%s: expected accumulated score %v, got %v", path, score, accumulated[key])
+		}
+	}
+}
+
+func TestAccumulatedScoresDecay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+
+	scores := AccumulatedScores{"/old.go": 10, "/gone.go": 7}
+
+	err := saveAccumulatedScores(
+		path, scores,
+		[]*File{{Path: "/old.go", Score: 20}, {Path: "/new.go", Score: 4}},
+		0.5,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadAccumulatedScores(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := AccumulatedScores{"/old.go": 15, "/new.go": 4, "/gone.go": 7}
+	for key, score := range expected {
+		if loaded[key] != score {
+			t.Errorf("%s: expected %v, got %v", key, score, loaded[key])
+		}
+	}
+
+	for _, value := range []string{"0", "1", "1.5", "x"} {
+		_, err := parseAccumDecay(map[string]interface{}{"--accum-decay": value})
+		if err == nil {
+			t.Errorf("%s: expected invalid decay", value)
+		}
+	}
+}
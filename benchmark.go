@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+type RuleBenchmark struct {
+	Rule    Rule
+	Total   time.Duration
+	Matches int
+	Files   int
+}
+
+func newRuleBenchmarks(rules []Rule) []RuleBenchmark {
+	benchmarks := make([]RuleBenchmark, len(rules))
+	for i, rule := range rules {
+		benchmarks[i].Rule = rule
+	}
+
+	return benchmarks
+}
+
+func printRuleBenchmarks(output io.Writer, benchmarks []RuleBenchmark) {
+	sorted := make([]RuleBenchmark, len(benchmarks))
+	copy(sorted, benchmarks)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Total > sorted[j].Total
+	})
+
+	writer := tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "RULE\tTOTAL\tMATCHES\tAVG PER FILE")
+	for _, benchmark := range sorted {
+		var average time.Duration
+		if benchmark.Files > 0 {
+			average = benchmark.Total / time.Duration(benchmark.Files)
+		}
+
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%d\t%s\n",
+			benchmark.Rule, benchmark.Total, benchmark.Matches, average,
+		)
+	}
+
+	writer.Flush()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrintRuleBenchmarks(t *testing.T) {
+	tree := map[string]string{}
+	for i := 0; i < 50; i++ {
+		tree[fmt.Sprintf("file%d.go", i)] = "package main\n\nfunc main() {}\n"
+	}
+
+	chdirTemp(t, tree)
+
+	rules := []Rule{
+		newRule(t, Rule{Suffix: ".go", Score: 1}),
+		newRule(t, Rule{LinePattern: "func", Score: 2}),
+		newRule(t, Rule{Suffix: ".md", Score: 3}),
+	}
+
+	files := walkTemp(t, &Config{Rules: rules})
+
+	benchmarks := newRuleBenchmarks(rules)
+	applyRules(context.Background(), files, rules, benchmarks)
+
+	for i, benchmark := range benchmarks {
+		if benchmark.Total <= 0 {
+			t.Errorf("rule #%d: total time is not positive: %s", i+1, benchmark.Total)
+		}
+
+		if benchmark.Files != len(files) {
+			t.Errorf("rule #%d: files = %d, want %d", i+1, benchmark.Files, len(files))
+		}
+	}
+
+	if benchmarks[0].Matches != len(files) || benchmarks[2].Matches != 0 {
+		t.Errorf(
+			"unexpected matches: %d and %d",
+			benchmarks[0].Matches, benchmarks[2].Matches,
+		)
+	}
+
+	var output bytes.Buffer
+	printRuleBenchmarks(&output, benchmarks)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != len(rules)+1 {
+		t.Fatalf("report should contain header and every rule:\n%s", output.String())
+	}
+
+	for _, rule := range rules {
+		if !strings.Contains(output.String(), rule.String()) {
+			t.Errorf("rule %s is not listed in report:\n%s", rule, output.String())
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/reconquest/karma-go"
+)
+
+// ResultsCache holds scored files of previous run along with modification
+// times of every walked directory, which change whenever files are added,
+// removed or renamed; files edited in place are detected by their own size
+// and modification time.
+type ResultsCache struct {
+	Key   string           `json:"key"`
+	Dirs  map[string]int64 `json:"dirs"`
+	Files []*File          `json:"files"`
+}
+
+// getResultsCacheKey returns key which identifies configuration and flags
+// affecting scores.
+func getResultsCacheKey(
+	config *Config,
+	args map[string]interface{},
+) (string, error) {
+	hash, err := config.Hash()
+	if err != nil {
+		return "", err
+	}
+
+	sessionStart, err := getSessionStart(args)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"%s %v %v %d %v",
+		hash,
+		args["--like"],
+		args["--here"],
+		sessionStart.UnixNano(),
+		args["--ignore-case-output"],
+	), nil
+}
+
+// isResultsCacheable reports whether results can be cached, which is not the
+// case for replayed walk and for rules depending on time or on files outside
+// of walked directory.
+func isResultsCacheable(config *Config, args map[string]interface{}) bool {
+	if args["--replay-walk"] != nil {
+		return false
+	}
+
+	return !hasRule(config.Rules, func(rule Rule) bool {
+		return rule.DirRecency != "" ||
+			rule.Uncommitted != nil ||
+			rule.SizeChanged != "" ||
+			rule.Keywords != ""
+	})
+}
+
+// getDirModTimes returns modification times of given walked directories
+// along with every parent directory of files, since listed files come
+// without walked directories.
+func getDirModTimes(files []*File, walked []string) (map[string]int64, error) {
+	dirs := map[string]int64{}
+	for _, dir := range walked {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs[filepath.Clean(dir)] = info.ModTime().UnixNano()
+	}
+
+	for _, file := range files {
+		dir := filepath.Dir(filepath.Clean(file.Path))
+		for {
+			if _, ok := dirs[dir]; ok {
+				break
+			}
+
+			info, err := os.Stat(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			dirs[dir] = info.ModTime().UnixNano()
+
+			if dir == "." || dir == "/" {
+				break
+			}
+
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	return dirs, nil
+}
+
+// loadResultsCache returns cached files if cache has been written with the
+// same key and no directory or file has changed since then.
+func loadResultsCache(path string, key string) ([]*File, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var cache ResultsCache
+	err = json.Unmarshal(data, &cache)
+	if err != nil {
+		log.Warningf(err, "unable to decode results cache %s", path)
+		return nil, false
+	}
+
+	if cache.Key != key {
+		return nil, false
+	}
+
+	for dir, modTime := range cache.Dirs {
+		info, err := os.Stat(dir)
+		if err != nil || info.ModTime().UnixNano() != modTime {
+			return nil, false
+		}
+	}
+
+	for _, file := range cache.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil || info.Size() != file.Size ||
+			!info.ModTime().Equal(file.ModTime) {
+			return nil, false
+		}
+	}
+
+	return cache.Files, true
+}
+
+func saveResultsCache(
+	path string,
+	key string,
+	files []*File,
+	walked []string,
+) error {
+	dirs, err := getDirModTimes(files, walked)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to get modification times of directories",
+		)
+	}
+
+	data, err := json.Marshal(ResultsCache{
+		Key:   key,
+		Dirs:  dirs,
+		Files: files,
+	})
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to encode results cache",
+		)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to write %s", path,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultsCache(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":     "package main\n",
+		"docs/doc.md": "# docs\n",
+	})
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	save := func() {
+		files, dirs, err := walk(context.Background(), &Config{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = saveResultsCache(path, "key", files, dirs)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	save()
+
+	files, ok := loadResultsCache(path, "key")
+	if !ok || len(files) != 2 {
+		t.Fatalf("expected cache hit with 2 files, got %v %v", ok, getPaths(files))
+	}
+
+	if _, ok := loadResultsCache(path, "other key"); ok {
+		t.Errorf("cache with different key should not be used")
+	}
+
+	writeFile(t, "docs/new.md", "")
+
+	if _, ok := loadResultsCache(path, "key"); ok {
+		t.Errorf("cache should be invalidated by a new file")
+	}
+
+	save()
+
+	// in-place edit keeps modification time of directory intact
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+
+	if _, ok := loadResultsCache(path, "key"); ok {
+		t.Errorf("cache should be invalidated by edited file")
+	}
+
+	save()
+
+	// same size but different modification time
+	stale := time.Now().Add(-time.Hour)
+	err := os.Chtimes("main.go", stale, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := loadResultsCache(path, "key"); ok {
+		t.Errorf("cache should be invalidated by touched file")
+	}
+
+	err = os.Mkdir("empty", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	save()
+
+	// directory without files is walked, so its changes are noticed too
+	writeFile(t, "empty/new.go", "")
+
+	if _, ok := loadResultsCache(path, "key"); ok {
+		t.Errorf("cache should be invalidated by a new file in empty directory")
+	}
+}
+
+func TestResultsCacheKeySessionStart(t *testing.T) {
+	config := &Config{}
+
+	t.Setenv("PROLS_SESSION_START", "1000")
+
+	first, err := getResultsCacheKey(config, parseArgs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PROLS_SESSION_START", "2000")
+
+	second, err := getResultsCacheKey(config, parseArgs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Errorf("session start from environment should be a part of key")
+	}
+}
+
+func TestResultsCacheable(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	keywords := filepath.Join(t.TempDir(), "keywords")
+	writeFile(t, keywords, "todo\n")
+
+	for name, rule := range map[string]Rule{
+		"dir_recency":  {DirRecency: "1h", Score: 1},
+		"uncommitted":  {Uncommitted: boolPtr(true), Score: 1},
+		"size_changed": {SizeChanged: SizeChangeGrew, Score: 1},
+		"keywords":     {Keywords: keywords, Score: 1},
+	} {
+		config := &Config{Rules: []Rule{newRule(t, rule)}}
+		if isResultsCacheable(config, parseArgs(t)) {
+			t.Errorf("%s: results should not be cached", name)
+		}
+	}
+
+	config := &Config{Rules: []Rule{newRule(t, Rule{Suffix: ".go", Score: 1})}}
+
+	if !isResultsCacheable(config, parseArgs(t)) {
+		t.Errorf("results of path rules should be cached")
+	}
+
+	if isResultsCacheable(config, parseArgs(t, "--replay-walk", "walk.json")) {
+		t.Errorf("results of replayed walk should not be cached")
+	}
+}
+
+func TestResultsCacheSkipsWalk(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+	})
+
+	dir := t.TempDir()
+
+	config := filepath.Join(dir, "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "rules": [{"prefix": "a", "score": 10}]
+}`)
+
+	cache := filepath.Join(dir, "cache.json")
+
+	stdout, _, err := runProls(t, "-c", config, "--results-cache", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "b.go\na.go\n" {
+		t.Fatalf("unexpected output: %q", stdout)
+	}
+
+	// cached scores are served as is, so tampered scores show up in output
+	data, err := os.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results ResultsCache
+	err = json.Unmarshal(data, &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range results.Files {
+		file.Score = 0
+		if file.Path == "b.go" {
+			file.Score = 100
+		}
+	}
+
+	data, err = json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, cache, string(data))
+
+	stdout, _, err = runProls(
+		t, "-c", config, "--results-cache", cache, "--use-cache",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "a.go\nb.go\n" {
+		t.Errorf("cache hit should skip walk, got %q", stdout)
+	}
+
+	writeFile(t, cache, string(data))
+
+	stdout, stderr, err := runProls(
+		t, "-c", config, "--results-cache", cache, "--use-cache",
+		"--explain-threshold", "0",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "b.go\na.go\n" || !strings.Contains(stderr, "b.go: 0\n") {
+		t.Errorf(
+			"cache should not be used with --explain-threshold, got %q %q",
+			stdout, stderr,
+		)
+	}
+
+	writeFile(t, "c.go", "")
+
+	stdout, _, err = runProls(
+		t, "-c", config, "--results-cache", cache, "--use-cache",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "b.go\nc.go\na.go\n" {
+		t.Errorf("cache miss should rebuild results, got %q", stdout)
+	}
+}
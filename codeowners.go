@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+var codeownersPaths = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+type CodeownersEntry struct {
+	patterns []IgnorePattern
+	owners   []string
+}
+
+// loadCodeowners reads the first CODEOWNERS file found in locations
+// supported by GitHub, no entries are returned if there is no such file.
+func loadCodeowners() ([]CodeownersEntry, error) {
+	for _, path := range codeownersPaths {
+		file, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, karma.Format(
+				err,
+				"unable to open %s", path,
+			)
+		}
+
+		defer file.Close()
+
+		entries := []CodeownersEntry{}
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+
+			fields := strings.Fields(text)
+
+			patterns, err := parseIgnorePattern(fields[0], "", false)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"invalid pattern at %s:%d", path, line,
+				)
+			}
+
+			entries = append(entries, CodeownersEntry{
+				patterns: patterns,
+				owners:   fields[1:],
+			})
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read %s", path,
+			)
+		}
+
+		return entries, nil
+	}
+
+	return nil, nil
+}
+
+// getOwners returns owners of the last entry matching given path.
+func getOwners(entries []CodeownersEntry, path string) []string {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		for _, pattern := range entries[i].patterns {
+			if pattern.MatchPath(path) {
+				return entries[i].owners
+			}
+		}
+	}
+
+	return nil
+}
+
+func markOwners(files []*File) error {
+	entries, err := loadCodeowners()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		file.Owners = getOwners(entries, file.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOwners(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".github/CODEOWNERS": `
+# default owners
+*                @org/all
+*.go             @org/gophers
+/docs/           @org/docs
+docs/api/*.go    @alice @bob
+/legacy/
+`,
+		"README.md":        "",
+		"main.go":          "",
+		"cmd/tool/main.go": "",
+		"docs/guide.md":    "",
+		"docs/example.go":  "",
+		"docs/api/api.go":  "",
+		"docs/api/api.md":  "",
+		"legacy/old.go":    "",
+	})
+
+	files := walkTemp(t, &Config{})
+
+	err := markOwners(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, owners := range map[string][]string{
+		"README.md":        {"@org/all"},
+		"main.go":          {"@org/gophers"},
+		"cmd/tool/main.go": {"@org/gophers"},
+		"docs/guide.md":    {"@org/docs"},
+		"docs/example.go":  {"@org/docs"},
+		"docs/api/api.go":  {"@alice", "@bob"},
+		"docs/api/api.md":  {"@org/docs"},
+		"legacy/old.go":    {},
+	} {
+		file := findFile(files, path)
+		if len(owners) == 0 && len(file.Owners) == 0 {
+			continue
+		}
+
+		if !reflect.DeepEqual(file.Owners, owners) {
+			t.Errorf("%s: owners = %q, want %q", path, file.Owners, owners)
+		}
+	}
+
+	rule := newRule(t, Rule{Owner: "@bob", Score: 1})
+	for _, file := range files {
+		if rule.Pass(file) != (file.Path == "docs/api/api.go") {
+			t.Errorf("%s: owner rule passed = %v", file.Path, rule.Pass(file))
+		}
+	}
+}
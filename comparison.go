@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Comparison is a parsed form of values like "5", ">5" or "<0.5" which are
+// used by rules to compare numeric file properties.
+type Comparison struct {
+	value float64
+	sign  byte
+}
+
+func parseComparison(spec string) (Comparison, error) {
+	var comparison Comparison
+
+	if spec == "" {
+		return comparison, errors.New("empty value")
+	}
+
+	switch spec[0] {
+	case '<', '>':
+		comparison.sign = spec[0]
+		spec = spec[1:]
+	}
+
+	value, err := strconv.ParseFloat(spec, 64)
+	if err != nil {
+		return comparison, err
+	}
+
+	comparison.value = value
+
+	return comparison, nil
+}
+
+func (comparison Comparison) Match(actual float64) bool {
+	switch comparison.sign {
+	case '<':
+		return actual < comparison.value
+	case '>':
+		return actual > comparison.value
+	default:
+		return actual == comparison.value
+	}
+}
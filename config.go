@@ -1,37 +1,73 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
 	"github.com/go-yaml/yaml"
 	"github.com/kovetskiy/ko"
 	"github.com/reconquest/karma-go"
+	"golang.org/x/text/unicode/norm"
 )
 
 type Config struct {
-	Lister       []string `yaml:"lister"`
-	IgnoreDirs   []string `yaml:"ignore_dirs" required:"true"`
-	HideNegative bool     `yaml:"hide_negative"`
-	Rules        []Rule
-	Reverse      bool `yaml:"reverse"`
+	Lister       []string `yaml:"lister" json:"lister"`
+	IgnoreDirs   []string `yaml:"ignore_dirs" json:"ignore_dirs" required:"true"`
+	HideNegative bool     `yaml:"hide_negative" json:"hide_negative"`
+	SkipHidden   bool     `yaml:"skip_hidden" json:"skip_hidden"`
+	Rules        []Rule   `yaml:"rules" json:"rules"`
+	Reverse      bool     `yaml:"reverse" json:"reverse"`
+
+	PreSort []PreSort `yaml:"presort" json:"presort"`
+
+	ScoreBuckets []int `yaml:"score_buckets" json:"score_buckets"`
 
-	PreSort []PreSort `yaml:"presort"`
+	RootBoost int `yaml:"root_boost" json:"root_boost"`
+	LikeScore int `yaml:"like_score" json:"like_score"`
+
+	NormalizePaths string `yaml:"normalize_paths" json:"normalize_paths"`
+	normalizeForm  norm.Form
 }
 
 type PreSort struct {
-	Field   string
+	Field   string `yaml:"field" json:"field"`
 	depth   bool
 	path    bool
-	Reverse bool
+	Reverse bool `yaml:"reverse" json:"reverse"`
+}
+
+func getConfigUnmarshaller(path string) func([]byte, interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return json.Unmarshal
+	default:
+		return yaml.Unmarshal
+	}
 }
 
 func LoadConfig(path string) (*Config, error) {
 	var config Config
-	err := ko.Load(path, &config, yaml.Unmarshal)
+	err := ko.Load(path, &config, getConfigUnmarshaller(path))
 	if err != nil {
 		return nil, err
 	}
 
-	for i, rule := range config.Rules {
-		err := rule.init()
+	if config.NormalizePaths != "" {
+		config.normalizeForm, err = parseNormalizationForm(config.NormalizePaths)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range config.Rules {
+			normalizeRule(&config.Rules[i], config.normalizeForm)
+		}
+	}
+
+	for i := range config.Rules {
+		err := config.Rules[i].init()
 		if err != nil {
 			return nil, karma.Format(
 				err,
@@ -59,3 +95,19 @@ func LoadConfig(path string) (*Config, error) {
 
 	return &config, nil
 }
+
+// Hash returns stable hash of resolved configuration which changes whenever
+// anything affecting results is changed.
+func (config *Config) Hash() (string, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", karma.Format(
+			err,
+			"unable to serialize config",
+		)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
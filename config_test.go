@@ -0,0 +1,125 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlConfig := `
+ignore_dirs:
+    - .git
+hide_negative: true
+presort:
+    - field: depth
+      reverse: true
+rules:
+    - suffix: .go
+      depth: "<3"
+      score: 10
+    - binary: true
+      score: -10
+`
+
+	jsonConfig := `{
+    "ignore_dirs": [".git"],
+    "hide_negative": true,
+    "presort": [{"field": "depth", "reverse": true}],
+    "rules": [
+        {"suffix": ".go", "depth": "<3", "score": 10},
+        {"binary": true, "score": -10}
+    ]
+}`
+
+	paths := map[string]string{
+		"prols.conf": yamlConfig,
+		"prols.yaml": yamlConfig,
+		"prols.yml":  yamlConfig,
+		"prols.json": jsonConfig,
+	}
+
+	configs := map[string]*Config{}
+	for name, contents := range paths {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, contents)
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		configs[name] = config
+	}
+
+	expected := configs["prols.conf"]
+	if len(expected.Rules) != 2 || !expected.PreSort[0].depth {
+		t.Fatalf("unexpected config: %#v", expected)
+	}
+
+	if !expected.Rules[0].Pass(&File{Path: "a/b.go"}) ||
+		expected.Rules[0].Pass(&File{Path: "a/b/c.go"}) {
+		t.Errorf("rules should be initialized after loading")
+	}
+
+	for name, config := range configs {
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("%s: config differs:\n%#v\n%#v", name, config, expected)
+		}
+	}
+}
+
+func TestLoadConfigInvalidRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, path, `{
+    "ignore_dirs": [".git"],
+    "rules": [{"suffix": ".go", "score": 1}, {"depth": ">x", "score": 1}]
+}`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("invalid rule should be reported regardless of format")
+	}
+
+	if !strings.Contains(err.Error(), "invalid config rule #2") {
+		t.Errorf("error should name invalid rule: %s", err)
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	dir := t.TempDir()
+
+	load := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, contents)
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := config.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return hash
+	}
+
+	header := "ignore_dirs: [.git]\nrules:\n    - suffix: .go\n"
+
+	original := load("a.conf", header+"      score: 10\n")
+	same := load("b.conf", header+"      score: 10\n")
+	changed := load("c.conf", header+"      score: 11\n")
+
+	if original == "" || original != same {
+		t.Errorf("identical configs should have the same hash: %q, %q", original, same)
+	}
+
+	if original == changed {
+		t.Errorf("hash should change when rule changes")
+	}
+}
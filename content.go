@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	LineEndingLF    = "lf"
+	LineEndingCRLF  = "crlf"
+	LineEndingCR    = "cr"
+	LineEndingMixed = "mixed"
+)
+
+// maxContentSize limits how much of every file is read by content rules.
+const maxContentSize = 1024 * 1024
+
+// Content returns first maxContentSize bytes of file, contents are read once
+// and shared between all content rules until released.
+func (file *File) Content() ([]byte, error) {
+	if !file.contentRead {
+		file.contentRead = true
+		file.content, file.contentErr = readContent(file.Path)
+		if file.contentErr != nil {
+			file.fail(file.contentErr)
+		}
+	}
+
+	return file.content, file.contentErr
+}
+
+// releaseContent drops cached contents once file is scored, so contents of
+// only one file are kept in memory at a time; they are read again if needed.
+func (file *File) releaseContent() {
+	file.content = nil
+	file.contentErr = nil
+	file.contentRead = false
+}
+
+// Text returns file contents if file looks like a text file, binary and
+// unreadable files yield no contents.
+func (file *File) Text() []byte {
+	content, err := file.Content()
+	if err != nil {
+		if debug {
+			log.Debugf(err, "unable to read %s", file.Path)
+		}
+
+		return nil
+	}
+
+	if isBinaryContent(content) {
+		return nil
+	}
+
+	return content
+}
+
+// GetLineEnding returns predominant line ending style of text file, empty
+// string is returned for binary files and files without line breaks.
+func (file *File) GetLineEnding() string {
+	if !file.lineEndingRead {
+		file.lineEndingRead = true
+		file.LineEnding = detectLineEnding(file.Text())
+	}
+
+	return file.LineEnding
+}
+
+// GetNonPrintableRatio returns fraction of non-printable bytes among first
+// nonPrintableSampleSize bytes of file, false is returned if file can't be
+// read.
+func (file *File) GetNonPrintableRatio() (float64, bool) {
+	if !file.nonPrintableRead {
+		file.nonPrintableRead = true
+
+		// result is kept separately since contents and their error are
+		// released once file is scored
+		content, err := file.Content()
+		if err == nil {
+			ratio := getNonPrintableRatio(content)
+			file.NonPrintableRatio = &ratio
+		}
+	}
+
+	if file.NonPrintableRatio == nil {
+		return 0, false
+	}
+
+	return *file.NonPrintableRatio, true
+}
+
+// nonPrintableSampleSize limits how many bytes are inspected to calculate
+// non-printable ratio.
+const nonPrintableSampleSize = 64 * 1024
+
+// getNonPrintableRatio counts control bytes except usual whitespace, bytes
+// above ASCII range are considered printable since they form UTF-8 text.
+func getNonPrintableRatio(content []byte) float64 {
+	if len(content) > nonPrintableSampleSize {
+		content = content[:nonPrintableSampleSize]
+	}
+
+	if len(content) == 0 {
+		return 0
+	}
+
+	count := 0
+	for _, char := range content {
+		switch {
+		case char == '\t', char == '\n', char == '\r', char == '\f':
+		case char < 0x20, char == 0x7f:
+			count++
+		}
+	}
+
+	return float64(count) / float64(len(content))
+}
+
+func detectLineEnding(content []byte) string {
+	var lf, crlf, cr int
+
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\n':
+			lf++
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		}
+	}
+
+	styles := 0
+	style := ""
+
+	for _, candidate := range []struct {
+		count int
+		name  string
+	}{
+		{lf, LineEndingLF},
+		{crlf, LineEndingCRLF},
+		{cr, LineEndingCR},
+	} {
+		if candidate.count > 0 {
+			styles++
+			style = candidate.name
+		}
+	}
+
+	if styles > 1 {
+		return LineEndingMixed
+	}
+
+	return style
+}
+
+func readContent(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to open %s", path,
+		)
+	}
+
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxContentSize))
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read file %s", path,
+		)
+	}
+
+	return content, nil
+}
+
+// probeContent checks that file can be read without reading it whole.
+func probeContent(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to open %s", path,
+		)
+	}
+
+	defer file.Close()
+
+	_, err = file.Read(make([]byte, 1))
+	if err != nil && err != io.EOF {
+		return karma.Format(
+			err,
+			"unable to read file %s", path,
+		)
+	}
+
+	return nil
+}
+
+func isBinaryContent(content []byte) bool {
+	head := content
+	if len(head) > 512 {
+		head = head[:512]
+	}
+
+	return http.DetectContentType(head) == "application/octet-stream"
+}
+
+func countLines(content []byte, match func([]byte) bool) (int, int) {
+	matched := 0
+	total := 0
+
+	for len(content) > 0 {
+		line := content
+		if index := bytes.IndexByte(content, '\n'); index >= 0 {
+			line = content[:index]
+			content = content[index+1:]
+		} else {
+			content = nil
+		}
+
+		total++
+		if match(line) {
+			matched++
+		}
+	}
+
+	return matched, total
+}
+
+// getHeadLines returns given number of first lines of content.
+func getHeadLines(content []byte, lines int) []byte {
+	end := 0
+	for i := 0; i < lines && end < len(content); i++ {
+		index := bytes.IndexByte(content[end:], '\n')
+		if index < 0 {
+			return content
+		}
+
+		end += index + 1
+	}
+
+	return content[:end]
+}
+
+// loadKeywords reads list of keywords, one per line, from given file; empty
+// lines and lines starting with # are skipped.
+func loadKeywords(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to open %s", path,
+		)
+	}
+
+	defer file.Close()
+
+	keywords := [][]byte{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		keyword := bytes.TrimSpace(scanner.Bytes())
+		if len(keyword) == 0 || keyword[0] == '#' {
+			continue
+		}
+
+		keywords = append(keywords, append([]byte{}, keyword...))
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read %s", path,
+		)
+	}
+
+	return keywords, nil
+}
+
+func containsKeyword(content []byte, keywords [][]byte) bool {
+	for _, keyword := range keywords {
+		if bytes.Contains(content, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsAll reports whether content contains every of given substrings,
+// nil content of binary or unreadable file never matches.
+func containsAll(content []byte, substrings []string) bool {
+	if content == nil {
+		return false
+	}
+
+	for _, substring := range substrings {
+		if !bytes.Contains(content, []byte(substring)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasConflictMarkers reports whether content contains unresolved merge
+// conflict, i.e. all of <<<<<<<, ======= and >>>>>>> markers.
+func hasConflictMarkers(content []byte) bool {
+	var ours, separator, theirs bool
+
+	countLines(content, func(line []byte) bool {
+		line = bytes.TrimRight(line, "\r")
+
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<<")):
+			ours = true
+		case bytes.Equal(line, []byte("=======")):
+			separator = true
+		case bytes.HasPrefix(line, []byte(">>>>>>>")):
+			theirs = true
+		}
+
+		return false
+	})
+
+	return ours && separator && theirs
+}
+
+// markContentDuplicates marks files which contents are the same as contents
+// of some other file, only files of equal size are hashed and empty files are
+// never considered duplicates.
+func markContentDuplicates(files []*File) {
+	sizes := map[int64]int{}
+	for _, file := range files {
+		sizes[file.Size]++
+	}
+
+	type key struct {
+		size int64
+		hash [sha256.Size]byte
+	}
+
+	groups := map[key][]*File{}
+	for _, file := range files {
+		if file.Size == 0 || sizes[file.Size] < 2 {
+			continue
+		}
+
+		content, err := file.Content()
+		if err != nil {
+			continue
+		}
+
+		id := key{size: file.Size, hash: sha256.Sum256(content)}
+
+		file.releaseContent()
+
+		groups[id] = append(groups[id], file)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		for _, file := range group {
+			file.contentDuplicate = true
+		}
+	}
+}
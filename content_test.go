@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinePattern(t *testing.T) {
+	lines := func(debug int, total int) string {
+		return strings.Repeat("fmt.Println(x)\n", debug) +
+			strings.Repeat("x++\n", total-debug)
+	}
+
+	chdirTemp(t, map[string]string{
+		"clean.go":  lines(0, 10),
+		"single.go": lines(1, 10),
+		"noisy.go":  lines(5, 10),
+		"binary.go": "\x00\x01\x02" + lines(5, 10),
+	})
+
+	matching := newRule(t, Rule{LinePattern: `fmt\.Print`, Score: 1})
+	count := newRule(t, Rule{LinePattern: `fmt\.Print`, LineCount: ">2", Score: 1})
+	ratio := newRule(t, Rule{LinePattern: `fmt\.Print`, LineRatio: ">0.3", Score: 1})
+
+	for path, expected := range map[string][]bool{
+		"clean.go":  {false, false, false},
+		"single.go": {true, false, false},
+		"noisy.go":  {true, true, true},
+		"binary.go": {false, false, false},
+	} {
+		file := &File{Path: path}
+
+		for i, rule := range []Rule{matching, count, ratio} {
+			if rule.Pass(file) != expected[i] {
+				t.Errorf("%s: rule %s passed = %v", path, rule, !expected[i])
+			}
+		}
+	}
+}
+
+func TestApplyRulesReleasesContent(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go": "TODO\n",
+		"b.go": "done\n",
+	})
+
+	rules := []Rule{
+		newRule(t, Rule{LinePattern: "TODO", Score: 3}),
+		newRule(t, Rule{LinePattern: "TODO", Score: 4}),
+	}
+
+	files := applyRules(
+		context.Background(),
+		[]*File{{Path: "a.go"}, {Path: "b.go"}},
+		rules,
+		nil,
+	)
+
+	if files[0].Score != 7 || files[1].Score != 0 {
+		t.Errorf("unexpected scores: %d, %d", files[0].Score, files[1].Score)
+	}
+
+	for _, file := range files {
+		if file.content != nil || file.contentRead {
+			t.Errorf("%s: contents should be released after scoring", file.Path)
+		}
+	}
+
+	if files[0].Text() == nil {
+		t.Errorf("released contents should be read again on demand")
+	}
+}
+
+func TestLicenseHeader(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"licensed.go":   "// SPDX-License-Identifier: MIT\n\npackage main\n",
+		"unlicensed.go": "package main\n",
+		"late.go":       strings.Repeat("//\n", 12) + "// SPDX-License-Identifier: MIT\n",
+		"custom.go":     "// Copyright 2024 Example Corp.\npackage main\n",
+		"binary.bin":    "\x00\x00SPDX-License-Identifier: MIT\n",
+	})
+
+	present := newRule(t, Rule{LicenseHeader: boolPtr(true), Score: 1})
+	missing := newRule(t, Rule{LicenseHeader: boolPtr(false), Score: 1})
+	custom := newRule(t, Rule{
+		LicenseHeader:  boolPtr(true),
+		LicensePattern: `Copyright \d+`,
+		LicenseLines:   1,
+		Score:          1,
+	})
+
+	for path, expected := range map[string][]bool{
+		"licensed.go":   {true, false, false},
+		"unlicensed.go": {false, true, false},
+		"late.go":       {false, true, false},
+		"custom.go":     {false, true, true},
+		"binary.bin":    {false, false, false},
+	} {
+		file := &File{Path: path}
+
+		for i, rule := range []Rule{present, missing, custom} {
+			if rule.Pass(file) != expected[i] {
+				t.Errorf("%s: rule %s passed = %v", path, rule, !expected[i])
+			}
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"fix.go":          "// PROJ-123: handle empty input\n",
+		"unrelated.go":    "package main\n",
+		"PROJ-456/api.go": "package api\n",
+		"partial.go":      "// PROJ-12\n",
+		"binary.bin":      "\x00\x00PROJ-123",
+	})
+
+	keywords := filepath.Join(t.TempDir(), "sprint.txt")
+	writeFile(t, keywords, "# current sprint\nPROJ-123\n\n  PROJ-456  \n")
+
+	rule := newRule(t, Rule{Keywords: keywords, Score: 1})
+
+	for path, expected := range map[string]bool{
+		"fix.go":          true,
+		"unrelated.go":    false,
+		"PROJ-456/api.go": true,
+		"partial.go":      false,
+		"binary.bin":      false,
+	} {
+		if rule.Pass(&File{Path: path}) != expected {
+			t.Errorf("%s: keywords passed = %v", path, !expected)
+		}
+	}
+
+	missing := Rule{Keywords: filepath.Join(t.TempDir(), "missing.txt"), Score: 1}
+	if missing.init() == nil {
+		t.Errorf("missing keywords file should be reported")
+	}
+}
+
+func TestErrored(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	chdirTemp(t, map[string]string{
+		"readable.txt": "contents\n",
+		"empty.txt":    "",
+		"locked.txt":   "secret\n",
+	})
+
+	err := os.Chmod("locked.txt", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []Rule{newRule(t, Rule{Errored: boolPtr(true)})}
+
+	files := walkTemp(t, &Config{Rules: rules})
+
+	err = markFiles(context.Background(), files, rules, "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := findFile(files, "locked.txt")
+	if !rules[0].Pass(locked) || locked.Error == "" {
+		t.Errorf("locked.txt: unreadable file should be flagged")
+	}
+
+	for _, path := range []string{"readable.txt", "empty.txt"} {
+		file := findFile(files, path)
+		if rules[0].Pass(file) {
+			t.Errorf("%s: readable file should not be flagged: %s", path, file.Error)
+		}
+
+		if file.contentRead {
+			t.Errorf("%s: contents should not be cached by probe", path)
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"unix.txt":    "one\ntwo\nthree\n",
+		"windows.txt": "one\r\ntwo\r\nthree\r\n",
+		"mac.txt":     "one\rtwo\r",
+		"mixed.txt":   "one\r\ntwo\nthree\r\n",
+		"single.txt":  "no line breaks",
+		"binary.bin":  "\x00\x01\x02\n\r\n\x00",
+	})
+
+	expected := map[string]string{
+		"unix.txt":    LineEndingLF,
+		"windows.txt": LineEndingCRLF,
+		"mac.txt":     LineEndingCR,
+		"mixed.txt":   LineEndingMixed,
+		"single.txt":  "",
+		"binary.bin":  "",
+	}
+
+	files := walkTemp(t, &Config{})
+
+	for path, ending := range expected {
+		if actual := findFile(files, path).GetLineEnding(); actual != ending {
+			t.Errorf("%s: expected line ending %q, got %q", path, ending, actual)
+		}
+	}
+
+	rule := newRule(t, Rule{LineEnding: LineEndingCRLF})
+
+	for path, ending := range expected {
+		if rule.Pass(findFile(files, path)) != (ending == LineEndingCRLF) {
+			t.Errorf("%s: crlf rule should match only crlf files", path)
+		}
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n" +
+			"package api\n",
+		"schema.js": "/* @generated by codegen */\nexport {}\n",
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"late.go": "package main\n" + strings.Repeat("\n", 20) +
+			"// Code generated by hand. DO NOT EDIT.\n",
+		"binary.bin":   "\x00\x01// Code generated x DO NOT EDIT.\n",
+		"custom.proto": "# autogenerated, keep away\n",
+	})
+
+	rule := newRule(t, Rule{Generated: boolPtr(true)})
+	custom := newRule(t, Rule{
+		Generated:         boolPtr(true),
+		GeneratedPatterns: []string{`autogenerated`},
+	})
+
+	files := walkTemp(t, &Config{})
+
+	expected := map[string]bool{
+		"api.pb.go":    true,
+		"schema.js":    true,
+		"main.go":      false,
+		"late.go":      false,
+		"binary.bin":   false,
+		"custom.proto": false,
+	}
+
+	for path, generated := range expected {
+		if rule.Pass(findFile(files, path)) != generated {
+			t.Errorf("%s: expected generated %v", path, generated)
+		}
+	}
+
+	if !custom.Pass(findFile(files, "custom.proto")) {
+		t.Errorf("custom.proto: custom pattern should match")
+	}
+
+	if custom.Pass(findFile(files, "api.pb.go")) {
+		t.Errorf("api.pb.go: custom patterns should replace default ones")
+	}
+
+	invalid := Rule{Generated: boolPtr(true), GeneratedPatterns: []string{"("}}
+	if invalid.init() == nil {
+		t.Errorf("invalid generated pattern should be rejected")
+	}
+}
+
+func TestConflictMarkers(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"conflict.go": "package main\n" +
+			"<<<<<<< HEAD\nconst a = 1\n=======\nconst a = 2\n>>>>>>> feature\n",
+		"crlf.txt":   "<<<<<<< HEAD\r\nours\r\n=======\r\ntheirs\r\n>>>>>>> main\r\n",
+		"clean.go":   "package main\n\nconst a = 1\n",
+		"heading.md": "Title\n=======\n\ntext\n",
+		"binary.bin": "\x00\x01<<<<<<< HEAD\n=======\n>>>>>>> x\n",
+	})
+
+	rule := newRule(t, Rule{HasConflictMarkers: boolPtr(true)})
+
+	files := walkTemp(t, &Config{})
+
+	expected := map[string]bool{
+		"conflict.go": true,
+		"crlf.txt":    true,
+		"clean.go":    false,
+		"heading.md":  false,
+		"binary.bin":  false,
+	}
+
+	for path, conflicted := range expected {
+		if rule.Pass(findFile(files, path)) != conflicted {
+			t.Errorf("%s: expected conflict markers %v", path, conflicted)
+		}
+	}
+}
+
+func TestNonPrintableRatio(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"clean.txt":  "plain text\twith tabs\r\nand lines\n",
+		"binary.bin": "\x00\x01\x02\x03\x04\x05\x06\x07",
+		"mostly.txt": strings.Repeat("a", 90) + strings.Repeat("\x1b", 10),
+		"utf8.txt":   "привет, мир\n",
+		"empty.txt":  "",
+	})
+
+	files := walkTemp(t, &Config{})
+
+	expected := map[string]float64{
+		"clean.txt":  0,
+		"binary.bin": 1,
+		"mostly.txt": 0.1,
+		"utf8.txt":   0,
+		"empty.txt":  0,
+	}
+
+	for path, ratio := range expected {
+		actual, ok := findFile(files, path).GetNonPrintableRatio()
+		if !ok || actual != ratio {
+			t.Errorf("%s: expected ratio %v, got %v %v", path, ratio, actual, ok)
+		}
+	}
+
+	mixed := newRule(t, Rule{NonPrintableRatio: ">0.05"})
+	text := newRule(t, Rule{NonPrintableRatio: "<0.05"})
+
+	for path, ratio := range expected {
+		file := findFile(files, path)
+
+		if mixed.Pass(file) != (ratio > 0.05) {
+			t.Errorf("%s: >0.05 passed = %v", path, mixed.Pass(file))
+		}
+
+		if text.Pass(file) != (ratio < 0.05) {
+			t.Errorf("%s: <0.05 passed = %v", path, text.Pass(file))
+		}
+	}
+
+	sample := strings.Repeat("a", nonPrintableSampleSize) + "\x00\x00\x00\x00"
+	if ratio := getNonPrintableRatio([]byte(sample)); ratio != 0 {
+		t.Errorf("bytes beyond sample should not be counted, got %v", ratio)
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"all.go":     "import \"net/http\"\n\nfunc main() { http.ListenAndServe() }\n",
+		"some.go":    "import \"net/http\"\n",
+		"none.go":    "package main\n",
+		"binary.bin": "\x00net/http\x00ListenAndServe",
+	})
+
+	rule := newRule(t, Rule{
+		ContainsAll: []string{"net/http", "ListenAndServe"},
+		Score:       10,
+	})
+
+	files := walkTemp(t, &Config{})
+
+	expected := map[string]bool{
+		"all.go":     true,
+		"some.go":    false,
+		"none.go":    false,
+		"binary.bin": false,
+	}
+
+	for path, matches := range expected {
+		if rule.Pass(findFile(files, path)) != matches {
+			t.Errorf("%s: expected match %v", path, matches)
+		}
+	}
+}
+
+func TestContentDuplicates(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a/util.go":      "package util\n",
+		"vendor/util.go": "package util\n",
+		"other.go":       "package othr\n",
+		"unique.go":      "package main\n\nfunc main() {}\n",
+		"empty1.txt":     "",
+		"empty2.txt":     "",
+	})
+
+	rule := newRule(t, Rule{IsContentDuplicate: boolPtr(true)})
+
+	files := walkTemp(t, &Config{})
+	markContentDuplicates(files)
+
+	expected := map[string]bool{
+		"a/util.go":      true,
+		"vendor/util.go": true,
+		"other.go":       false,
+		"unique.go":      false,
+		"empty1.txt":     false,
+		"empty2.txt":     false,
+	}
+
+	for path, duplicate := range expected {
+		file := findFile(files, path)
+
+		if rule.Pass(file) != duplicate {
+			t.Errorf("%s: expected duplicate %v", path, duplicate)
+		}
+
+		if file.contentRead {
+			t.Errorf("%s: contents should be released after hashing", path)
+		}
+	}
+}
+
+func TestNonPrintableRatioUnreadable(t *testing.T) {
+	chdirTemp(t, map[string]string{"gone.txt": "text"})
+
+	files := walkTemp(t, &Config{})
+
+	err := os.Remove("gone.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := findFile(files, "gone.txt")
+	rule := newRule(t, Rule{NonPrintableRatio: "<0.5"})
+
+	if rule.Pass(file) {
+		t.Errorf("unreadable file should not pass")
+	}
+
+	file.releaseContent()
+
+	if _, ok := file.GetNonPrintableRatio(); ok || rule.Pass(file) {
+		t.Errorf("unreadable file should not pass after contents are released")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/reconquest/karma-go"
+)
+
+// rankFiles walks directory and orders files according to given config the
+// same way they are printed.
+func rankFiles(
+	config *Config,
+	args map[string]interface{},
+) ([]*File, error) {
+	files, _, err := walk(context.Background(), config, false)
+	if err != nil {
+		return nil, karma.Format(err, "unable to walk directory")
+	}
+
+	if config.NormalizePaths != "" {
+		normalizeMatchPaths(files, config.normalizeForm)
+	}
+
+	sessionStart, err := getSessionStart(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = markFiles(
+		context.Background(),
+		files,
+		config.Rules,
+		args["--here"].(string),
+		sessionStart,
+	)
+	if err != nil {
+		return nil, karma.Format(err, "unable to prepare files")
+	}
+
+	if path, ok := args["--append-scores-to"].(string); ok {
+		accumulated, err := loadAccumulatedScores(path)
+		if err != nil {
+			return nil, karma.Format(err, "unable to load accumulated scores")
+		}
+
+		markAccumulatedScores(files, accumulated)
+	}
+
+	files = applyPreSort(files, config.PreSort)
+	files = applyRules(context.Background(), files, config.Rules, nil)
+	files = applyRootBoost(files, config.RootBoost)
+
+	likes, _ := args["--like"].([]string)
+	files = applyLikeScore(files, likes, config.LikeScore)
+	files = applySortScore(
+		files,
+		args["--unstable-sort"].(bool),
+		args["--reverse-ties"].(bool),
+	)
+
+	if config.Reverse {
+		files = reverseFiles(files)
+	}
+
+	return files, nil
+}
+
+func getContributions(file *File) map[string]int {
+	contributions := map[string]int{}
+	for _, contribution := range file.contributions {
+		contributions[contribution.Rule.String()] += contribution.Delta
+	}
+
+	return contributions
+}
+
+// explainConfigDiff prints files which position differs between given
+// config and config loaded from given path, and for each of them rules
+// which contributed differently to file's score.
+func explainConfigDiff(
+	output io.Writer,
+	config *Config,
+	path string,
+	args map[string]interface{},
+) error {
+	other, err := LoadConfig(path)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to load configuration file: %s", path,
+		)
+	}
+
+	// given config's rules are already selected, other one is compared
+	// using the same selection
+	other.Rules, err = selectRules(other.Rules, args)
+	if err != nil {
+		return karma.Format(err, "invalid rules selection")
+	}
+
+	before, err := rankFiles(config, args)
+	if err != nil {
+		return err
+	}
+
+	after, err := rankFiles(other, args)
+	if err != nil {
+		return err
+	}
+
+	ranks := map[string]int{}
+	files := map[string]*File{}
+	for rank, file := range before {
+		ranks[file.Path] = rank
+		files[file.Path] = file
+	}
+
+	for rank, file := range after {
+		previous, ok := files[file.Path]
+		if !ok || ranks[file.Path] == rank {
+			continue
+		}
+
+		fmt.Fprintf(
+			output,
+			"%s: position %d -> %d, score %d -> %d\n",
+			file.Path, ranks[file.Path]+1, rank+1, previous.Score, file.Score,
+		)
+
+		was := getContributions(previous)
+		now := getContributions(file)
+
+		rules := []string{}
+		for rule := range was {
+			rules = append(rules, rule)
+		}
+
+		for rule := range now {
+			if _, ok := was[rule]; !ok {
+				rules = append(rules, rule)
+			}
+		}
+
+		sort.Strings(rules)
+
+		for _, rule := range rules {
+			if was[rule] != now[rule] {
+				fmt.Fprintf(output, "  %+d -> %+d %s\n", was[rule], now[rule], rule)
+			}
+		}
+	}
+
+	return nil
+}
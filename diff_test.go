@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExplainConfigDiff(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	chdirTemp(t, map[string]string{
+		"main.go":   "package main\n",
+		"README.md": "# readme\n",
+		"notes.txt": "notes\n",
+		"other.conf": `
+ignore_dirs:
+    - .git
+rules:
+    - suffix: .go
+      score: 10
+    - suffix: .md
+      score: 20
+    - suffix: .txt
+      score: 5
+`,
+	})
+
+	config := &Config{
+		Rules: []Rule{
+			newRule(t, Rule{Suffix: ".go", Score: 30}),
+			newRule(t, Rule{Suffix: ".md", Score: 20}),
+			newRule(t, Rule{Suffix: ".txt", Score: 5}),
+		},
+	}
+
+	output := &bytes.Buffer{}
+
+	err := explainConfigDiff(
+		output, config, "other.conf",
+		parseArgs(t, "--config-diff-explain", "other.conf"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+
+	expected := []string{
+		"main.go: position 4 -> 3, score 30 -> 10",
+		"  +0 -> +10 " + Rule{Suffix: ".go", Score: 10}.String(),
+		"  +30 -> +0 " + config.Rules[0].String(),
+		"README.md: position 3 -> 4, score 20 -> 20",
+	}
+
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf(
+			"unexpected explanation:\n%s\nexpected:\n%s",
+			output.String(), strings.Join(expected, "\n"),
+		)
+	}
+}
+
+func TestExplainConfigDiffSelectedRules(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	chdirTemp(t, map[string]string{
+		"main.go":   "package main\n",
+		"README.md": "# readme\n",
+		"other.conf": `
+ignore_dirs:
+    - .git
+rules:
+    - suffix: .go
+      score: 10
+    - suffix: .md
+      score: 50
+`,
+	})
+
+	// config is loaded with --head-rules already applied
+	config := &Config{
+		Rules: []Rule{newRule(t, Rule{Suffix: ".go", Score: 10})},
+	}
+
+	output := &bytes.Buffer{}
+
+	err := explainConfigDiff(
+		output, config, "other.conf",
+		parseArgs(t, "--config-diff-explain", "other.conf", "--head-rules", "1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Len() != 0 {
+		t.Errorf("rules past --head-rules should not be compared, got:\n%s", output)
+	}
+}
+
+func TestExplainConfigDiffKeepsSnapshot(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	chdirTemp(t, map[string]string{
+		"app.log":  "started\n",
+		"data.csv": "a,b\n",
+		"other.conf": `
+ignore_dirs:
+    - .git
+rules:
+    - size_changed: grew
+      score: 5
+`,
+	})
+
+	config := &Config{
+		Rules: []Rule{newRule(t, Rule{SizeChanged: SizeChangeGrew, Score: 10})},
+	}
+
+	files := walkTemp(t, config)
+
+	err := saveSizes(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := getSnapshotPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, "app.log", "started\nrunning\n")
+
+	output := &bytes.Buffer{}
+
+	err = explainConfigDiff(
+		output, config, "other.conf",
+		parseArgs(t, "--config-diff-explain", "other.conf"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Len() != 0 {
+		t.Errorf("grown file should be ranked first by both configs, got:\n%s", output)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(snapshot, after) {
+		t.Errorf("sizes snapshot should not be updated")
+	}
+}
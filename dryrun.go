@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// estimateContentReads returns how many times files would be opened to
+// evaluate given rules; every file's contents are read at most once per
+// kind of check.
+func estimateContentReads(files []*File, rules []Rule) int {
+	detectType := hasRule(rules, func(rule Rule) bool {
+		return rule.Binary != nil
+	})
+
+	goParses := hasRule(rules, func(rule Rule) bool {
+		return rule.GoParses != nil
+	})
+
+	content := hasRule(rules, func(rule Rule) bool {
+		return rule.readsContent() || rule.Errored != nil
+	})
+
+	reads := 0
+	for _, file := range files {
+		if detectType {
+			reads++
+		}
+
+		if goParses && strings.HasSuffix(file.Path, ".go") {
+			reads++
+		}
+
+		if content {
+			reads++
+		}
+	}
+
+	return reads
+}
+
+func printDryRun(output io.Writer, files []*File, rules []Rule) {
+	fmt.Fprintf(output, "files: %d\n", len(files))
+	fmt.Fprintf(output, "content reads: %d\n", estimateContentReads(files, rules))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go":           "package a\n",
+		"b.go":           "package b\n",
+		"c/c.go":         "package c\n",
+		"README.md":      "readme\n",
+		"image.png":      "\x89PNG\x00\x00",
+		".git/config":    "",
+		".prolsignore":   "*.log\n",
+		"debug.log":      "",
+		"c/notes.md":     "notes\n",
+		"c/.prolsignore": "notes.md\n",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "rules": [
+        {"binary": true, "score": -10},
+        {"go_parses": true, "score": 1},
+        {"line_pattern": "TODO", "score": 1}
+    ]
+}`)
+
+	stdout, _, err := runProls(t, "-c", config, "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 7 files are left after ignoring, every one is opened to detect type
+	// and by line_pattern, three .go files are parsed as well
+	expected := "files: 7\ncontent reads: 17\n"
+	if stdout != expected {
+		t.Errorf("unexpected summary:\n%s\nwant:\n%s", stdout, expected)
+	}
+
+	loaded, err := LoadConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _, err := walk(context.Background(), loaded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if findFile(files, "image.png").Binary {
+		t.Errorf("type should not be detected during dry run")
+	}
+}
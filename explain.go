@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Contribution is a score added to file by a single rule.
+type Contribution struct {
+	Index int
+	Rule  Rule
+	Delta int
+}
+
+// scoreCutoff is the lowest score of files kept when hide_negative is set.
+const scoreCutoff = 0
+
+func explainFile(output io.Writer, file *File) {
+	fmt.Fprintf(output, "%s: %d\n", file.Path, file.Score)
+	for _, contribution := range file.contributions {
+		fmt.Fprintf(output, "  %+d %s\n", contribution.Delta, contribution.Rule)
+	}
+}
+
+// explainThreshold explains files which score is within given distance from
+// score cutoff, those are files that are most likely to be kept or dropped
+// by a small change of rules.
+func explainThreshold(output io.Writer, files []*File, threshold int) {
+	for _, file := range files {
+		distance := file.Score - scoreCutoff
+		if distance < 0 {
+			distance = -distance
+		}
+
+		if distance <= threshold {
+			explainFile(output, file)
+		}
+	}
+}
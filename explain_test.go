@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExplainThreshold(t *testing.T) {
+	rules := []Rule{
+		newRule(t, Rule{Suffix: ".go", Score: 10}),
+		newRule(t, Rule{Prefix: "vendor/", Score: -9}),
+		newRule(t, Rule{Suffix: ".md", Score: -2}),
+		newRule(t, Rule{Suffix: ".bin", Score: -50}),
+	}
+
+	files := applyRules(
+		context.Background(),
+		[]*File{
+			{Path: "main.go"},
+			{Path: "vendor/lib.go"},
+			{Path: "README.md"},
+			{Path: "blob.bin"},
+		},
+		rules,
+		nil,
+	)
+
+	var output bytes.Buffer
+	explainThreshold(&output, files, 3)
+
+	expected := "vendor/lib.go: 1\n" +
+		"  +10 " + rules[0].String() + "\n" +
+		"  -9 " + rules[1].String() + "\n" +
+		"README.md: -2\n" +
+		"  -2 " + rules[2].String() + "\n"
+
+	if output.String() != expected {
+		t.Errorf("unexpected explanation:\n%s\nwant:\n%s", output.String(), expected)
+	}
+
+	if strings.Contains(output.String(), "main.go") ||
+		strings.Contains(output.String(), "blob.bin") {
+		t.Errorf("files far from cutoff should not be explained")
+	}
+}
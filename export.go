@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/reconquest/karma-go"
+)
+
+// getFeatureColumns returns names of exported features, every rule gets
+// its own column named after its position in config.
+func getFeatureColumns(rules []Rule) []string {
+	columns := []string{"path", "depth", "size", "age"}
+	for i := range rules {
+		columns = append(columns, fmt.Sprintf("rule_%d", i+1))
+	}
+
+	return append(columns, "match_count", "score")
+}
+
+func getFeatures(file *File, rules []Rule, now time.Time) []interface{} {
+	matches := make([]bool, len(rules))
+	for _, contribution := range file.contributions {
+		matches[contribution.Index] = true
+	}
+
+	features := []interface{}{
+		file.Path,
+		file.Depth(),
+		file.Size,
+		int64(now.Sub(file.ModTime).Seconds()),
+	}
+
+	for _, match := range matches {
+		features = append(features, match)
+	}
+
+	return append(features, len(file.contributions), file.Score)
+}
+
+// exportFeatures writes per-file features to given path, as JSON lines if
+// path ends with .jsonl and as CSV otherwise.
+func exportFeatures(path string, files []*File, rules []Rule) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to create %s", path,
+		)
+	}
+
+	defer output.Close()
+
+	columns := getFeatureColumns(rules)
+	now := time.Now()
+
+	if strings.ToLower(filepath.Ext(path)) == ".jsonl" {
+		encoder := json.NewEncoder(output)
+		for _, file := range files {
+			row := map[string]interface{}{}
+			for i, feature := range getFeatures(file, rules, now) {
+				row[columns[i]] = feature
+			}
+
+			err := encoder.Encode(row)
+			if err != nil {
+				return karma.Format(
+					err,
+					"unable to write %s", path,
+				)
+			}
+		}
+
+		return nil
+	}
+
+	writer := csv.NewWriter(output)
+	writer.Write(columns)
+
+	for _, file := range files {
+		row := []string{}
+		for _, feature := range getFeatures(file, rules, now) {
+			switch value := feature.(type) {
+			case bool:
+				if value {
+					row = append(row, "1")
+				} else {
+					row = append(row, "0")
+				}
+			default:
+				row = append(row, fmt.Sprint(value))
+			}
+		}
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+
+	err = writer.Error()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to write %s", path,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportFeatures(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":     "package main\n",
+		"docs/doc.md": "# docs\n",
+	})
+
+	rules := []Rule{
+		newRule(t, Rule{Suffix: ".go", Score: 10}),
+		newRule(t, Rule{Prefix: "docs/", Score: 5}),
+		newRule(t, Rule{Score: 1}),
+	}
+
+	files := walkTemp(t, &Config{Rules: rules})
+	files = applyRules(context.Background(), files, rules, nil)
+
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "features.csv")
+
+	err := exportFeatures(csvPath, files, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvFile, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer csvFile.Close()
+
+	records, err := csv.NewReader(csvFile).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []string{
+		"path", "depth", "size", "age",
+		"rule_1", "rule_2", "rule_3",
+		"match_count", "score",
+	}
+
+	if !reflect.DeepEqual(records[0], columns) {
+		t.Fatalf("unexpected columns: %v", records[0])
+	}
+
+	rows := map[string][]string{}
+	for _, record := range records[1:] {
+		rows[record[0]] = record
+	}
+
+	expected := map[string][]string{
+		"main.go":     {"main.go", "1", "13", "1", "0", "1", "2", "11"},
+		"docs/doc.md": {"docs/doc.md", "2", "7", "0", "1", "1", "2", "6"},
+	}
+
+	for path, values := range expected {
+		row, ok := rows[path]
+		if !ok {
+			t.Fatalf("%s: row is missing", path)
+		}
+
+		// age depends on time of test run
+		actual := append(append([]string{}, row[:3]...), row[4:]...)
+		if !reflect.DeepEqual(actual, values) {
+			t.Errorf("%s: unexpected features: %v", path, row)
+		}
+	}
+
+	jsonPath := filepath.Join(dir, "features.jsonl")
+
+	err = exportFeatures(jsonPath, files, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonFile, err := os.Open(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer jsonFile.Close()
+
+	lines := 0
+
+	scanner := bufio.NewScanner(jsonFile)
+	for scanner.Scan() {
+		row := map[string]interface{}{}
+
+		err := json.Unmarshal(scanner.Bytes(), &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, column := range columns {
+			if _, ok := row[column]; !ok {
+				t.Errorf("%v: column %s is missing", row["path"], column)
+			}
+		}
+
+		if row["path"] == "main.go" && row["rule_1"] != true {
+			t.Errorf("main.go: rule_1 should be passed: %v", row)
+		}
+
+		lines++
+	}
+
+	if lines != len(files) {
+		t.Errorf("expected %d rows, got %d", len(files), lines)
+	}
+}
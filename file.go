@@ -1,20 +1,70 @@
 package main
 
 import (
+	"go/parser"
+	"go/token"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/reconquest/karma-go"
 )
 
+// File is a walked file; properties which are calculated only for rules
+// using them are nil until calculated, so they are omitted from output.
 type File struct {
-	Path   string
-	Binary bool
-	Score  int
-	depth  int
+	Path              string      `json:"path"`
+	Binary            bool        `json:"binary"`
+	GitIgnored        *bool       `json:"gitignored,omitempty"`
+	Sparse            bool        `json:"sparse"`
+	Links             int         `json:"links"`
+	Mode              os.FileMode `json:"mode"`
+	Size              int64       `json:"size"`
+	RepoDepth         *int        `json:"repo_depth,omitempty"`
+	ModTime           time.Time   `json:"mod_time"`
+	CommitTime        *time.Time  `json:"commit_time,omitempty"`
+	SessionModified   *bool       `json:"session_modified,omitempty"`
+	InBuildManifest   *bool       `json:"in_build_manifest,omitempty"`
+	Error             string      `json:"error,omitempty"`
+	LineEnding        string      `json:"line_ending,omitempty"`
+	NonPrintableRatio *float64    `json:"non_printable_ratio,omitempty"`
+	Owners            []string    `json:"owners,omitempty"`
+	Score             int         `json:"score"`
+	depth             int
+	matchPath         string
+	goParses          *bool
+
+	sizePercentile   float64
+	dirSizeRatio     float64
+	hereDistance     int
+	dirModTime       time.Time
+	firstInDir       bool
+	lastInDir        bool
+	previousSize     *int64
+	contentDuplicate bool
+	accumulatedScore float64
+	contributions    []Contribution
+
+	content     []byte
+	contentErr  error
+	contentRead bool
+
+	lineEndingRead   bool
+	nonPrintableRead bool
+}
+
+// MatchPath returns path which should be used by rules, it differs from
+// file's path when paths are normalized.
+func (file *File) MatchPath() string {
+	if file.matchPath != "" {
+		return file.matchPath
+	}
+
+	return file.Path
 }
 
 func (file *File) Depth() int {
@@ -25,6 +75,150 @@ func (file *File) Depth() int {
 	return file.depth
 }
 
+// getPathKey returns key which identifies file for deduplication purposes.
+func getPathKey(path string, ignoreCase bool) string {
+	key := filepath.Clean(path)
+	if ignoreCase {
+		key = strings.ToLower(key)
+	}
+
+	return key
+}
+
+// uniqueFiles drops files which refer to already seen path, the first seen
+// spelling of path is kept.
+func uniqueFiles(files []*File, ignoreCase bool) []*File {
+	seen := map[string]struct{}{}
+	unique := []*File{}
+
+	for _, file := range files {
+		key := getPathKey(file.Path, ignoreCase)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		unique = append(unique, file)
+	}
+
+	return unique
+}
+
+// markSizePercentiles calculates for every file percentage of files which
+// are smaller than it.
+func markSizePercentiles(files []*File) {
+	sizes := make([]int64, len(files))
+	for i, file := range files {
+		sizes[i] = file.Size
+	}
+
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i] < sizes[j]
+	})
+
+	for _, file := range files {
+		smaller := sort.Search(len(sizes), func(i int) bool {
+			return sizes[i] >= file.Size
+		})
+
+		file.sizePercentile = 100 * float64(smaller) / float64(len(sizes))
+	}
+}
+
+// markDirSizeRatios calculates for every file which part of total size of
+// files in its directory it takes.
+func markDirSizeRatios(files []*File) {
+	totals := map[string]int64{}
+	for _, file := range files {
+		totals[filepath.Dir(file.Path)] += file.Size
+	}
+
+	for _, file := range files {
+		total := totals[filepath.Dir(file.Path)]
+		if total > 0 {
+			file.dirSizeRatio = float64(file.Size) / float64(total)
+		}
+	}
+}
+
+// markDirModTimes finds for every file the latest modification time among
+// files in the same directory.
+func markDirModTimes(files []*File) {
+	latest := map[string]time.Time{}
+	for _, file := range files {
+		dir := filepath.Dir(file.Path)
+		if file.ModTime.After(latest[dir]) {
+			latest[dir] = file.ModTime
+		}
+	}
+
+	for _, file := range files {
+		file.dirModTime = latest[filepath.Dir(file.Path)]
+	}
+}
+
+// markDirPositions marks files which go first and last in their directory
+// when ordered by name.
+func markDirPositions(files []*File) {
+	first := map[string]*File{}
+	last := map[string]*File{}
+
+	for _, file := range files {
+		dir := filepath.Dir(file.Path)
+		name := filepath.Base(file.Path)
+
+		if current, ok := first[dir]; !ok || name < filepath.Base(current.Path) {
+			first[dir] = file
+		}
+
+		if current, ok := last[dir]; !ok || name > filepath.Base(current.Path) {
+			last[dir] = file
+		}
+	}
+
+	for _, file := range first {
+		file.firstInDir = true
+	}
+
+	for _, file := range last {
+		file.lastInDir = true
+	}
+}
+
+// markSessionModified marks files modified after given session start, no
+// files are marked if session start is unknown.
+func markSessionModified(files []*File, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+
+	for _, file := range files {
+		modified := file.ModTime.After(start)
+		file.SessionModified = &modified
+	}
+}
+
+// fail records error which happened while processing file, only the first
+// error is kept.
+func (file *File) fail(err error) {
+	if file.Error == "" {
+		file.Error = err.Error()
+	}
+}
+
+// GoParses reports whether file is syntactically valid Go source, result is
+// cached since several rules can ask for it.
+func (file *File) GoParses() bool {
+	if file.goParses == nil {
+		_, err := parser.ParseFile(token.NewFileSet(), file.Path, nil, 0)
+
+		parses := err == nil
+		file.goParses = &parses
+	}
+
+	return *file.goParses
+}
+
 func detectType(
 	base string,
 	path string,
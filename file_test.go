@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoParses(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"valid.go":  "package main\n\nfunc main() {}\n",
+		"broken.go": "package main\n\nfunc main() {\n",
+		"notes.txt": "package main\n",
+	})
+
+	parses := newRule(t, Rule{GoParses: boolPtr(true), Score: 1})
+	broken := newRule(t, Rule{GoParses: boolPtr(false), Score: 1})
+
+	for path, valid := range map[string]bool{
+		"valid.go":  true,
+		"broken.go": false,
+	} {
+		file := &File{Path: path}
+
+		if parses.Pass(file) != valid {
+			t.Errorf("%s: go_parses: true passed = %v", path, !valid)
+		}
+
+		if broken.Pass(file) == valid {
+			t.Errorf("%s: go_parses: false passed = %v", path, valid)
+		}
+	}
+
+	file := &File{Path: "notes.txt"}
+	if parses.Pass(file) || broken.Pass(file) {
+		t.Errorf("files with other extensions should never pass")
+	}
+}
+
+func TestUniqueFilesIgnoreCase(t *testing.T) {
+	files := []*File{
+		{Path: "src/Main.go"},
+		{Path: "src/main.go"},
+		{Path: "./SRC/MAIN.GO"},
+		{Path: "src/util.go"},
+	}
+
+	unique := uniqueFiles(files, true)
+	if len(unique) != 2 {
+		t.Fatalf("got %v, want 2 files", getPaths(unique))
+	}
+
+	if unique[0].Path != "src/Main.go" {
+		t.Errorf("first seen spelling should be kept, got %s", unique[0].Path)
+	}
+
+	if len(uniqueFiles(files, false)) != len(files) {
+		t.Errorf("files differing in case should be kept when case matters")
+	}
+}
+
+func TestSizePercentile(t *testing.T) {
+	tree := map[string]string{}
+	for i := 1; i <= 10; i++ {
+		tree[string(rune('a'+i-1))+".txt"] = strings.Repeat("x", i*100)
+	}
+
+	chdirTemp(t, tree)
+
+	files := walkTemp(t, &Config{})
+	markSizePercentiles(files)
+
+	top := newRule(t, Rule{SizePercentile: ">80", Score: 1})
+	bottom := newRule(t, Rule{SizePercentile: "<20", Score: 1})
+
+	for _, file := range files {
+		if top.Pass(file) != (file.Path == "j.txt") {
+			t.Errorf("%s: >80 passed = %v", file.Path, top.Pass(file))
+		}
+
+		if bottom.Pass(file) != (file.Path == "a.txt" || file.Path == "b.txt") {
+			t.Errorf("%s: <20 passed = %v", file.Path, bottom.Pass(file))
+		}
+	}
+}
+
+func TestDirRecency(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"hot/new.go":   "",
+		"hot/old.go":   "",
+		"cold/old.go":  "",
+		"cold/also.go": "",
+	})
+
+	stale := time.Now().Add(-10 * 24 * time.Hour)
+	for _, path := range []string{"hot/old.go", "cold/old.go", "cold/also.go"} {
+		err := os.Chtimes(path, stale, stale)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := []Rule{newRule(t, Rule{DirRecency: "72h", Score: 100})}
+
+	files := walkTemp(t, &Config{Rules: rules})
+	markDirModTimes(files)
+	files = applyRules(context.Background(), files, rules, nil)
+
+	for _, path := range []string{"hot/new.go", "hot/old.go"} {
+		if score := findFile(files, path).Score; score < 90 || score > 100 {
+			t.Errorf("%s: file in recently changed dir should be boosted, got %d", path, score)
+		}
+	}
+
+	for _, path := range []string{"cold/old.go", "cold/also.go"} {
+		if score := findFile(files, path).Score; score != 0 {
+			t.Errorf("%s: file in stale dir should not be boosted, got %d", path, score)
+		}
+	}
+}
+
+func TestSessionModified(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"before.go": "",
+		"after.go":  "",
+	})
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	times := map[string]time.Time{
+		"before.go": start.Add(-time.Minute),
+		"after.go":  start.Add(time.Minute),
+	}
+
+	for path, modTime := range times {
+		err := os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, value := range []string{
+		fmt.Sprint(start.Unix()),
+		start.Format(time.RFC3339),
+	} {
+		sessionStart, err := getSessionStart(
+			map[string]interface{}{"--session-start": value},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rules := []Rule{newRule(t, Rule{SessionModified: boolPtr(true)})}
+
+		files := walkTemp(t, &Config{Rules: rules})
+
+		err = markFiles(context.Background(), files, rules, "", sessionStart)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !rules[0].Pass(findFile(files, "after.go")) {
+			t.Errorf("%s: file modified after session start should match", value)
+		}
+
+		if rules[0].Pass(findFile(files, "before.go")) {
+			t.Errorf("%s: file modified before session start should not match", value)
+		}
+	}
+}
+
+func TestSessionStartEnv(t *testing.T) {
+	t.Setenv("PROLS_SESSION_START", "1700000000")
+
+	start, err := getSessionStart(map[string]interface{}{"--session-start": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !start.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected session start from environment, got %s", start)
+	}
+
+	_, err = getSessionStart(
+		map[string]interface{}{"--session-start": "yesterday"},
+	)
+	if err == nil {
+		t.Errorf("expected error for invalid session start")
+	}
+}
+
+func TestPositionInDir(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"README.md":      "",
+		"main.go":        "",
+		"docs/index.md":  "",
+		"docs/intro.md":  "",
+		"docs/usage.md":  "",
+		"single/only.go": "",
+	})
+
+	first := newRule(t, Rule{PositionInDir: PositionFirst})
+	last := newRule(t, Rule{PositionInDir: PositionLast})
+
+	files := walkTemp(t, &Config{})
+	markDirPositions(files)
+
+	tests := []struct {
+		path  string
+		first bool
+		last  bool
+	}{
+		{"README.md", true, false},
+		{"main.go", false, true},
+		{"docs/index.md", true, false},
+		{"docs/intro.md", false, false},
+		{"docs/usage.md", false, true},
+		{"single/only.go", true, true},
+	}
+
+	for _, test := range tests {
+		file := findFile(files, test.path)
+
+		if first.Pass(file) != test.first {
+			t.Errorf("%s: first: expected %v", test.path, test.first)
+		}
+
+		if last.Pass(file) != test.last {
+			t.Errorf("%s: last: expected %v", test.path, test.last)
+		}
+	}
+}
+
+func TestDirSizeRatio(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"lib/big.go":    strings.Repeat("x", 800),
+		"lib/small1.go": strings.Repeat("x", 100),
+		"lib/small2.go": strings.Repeat("x", 50),
+		"lib/small3.go": strings.Repeat("x", 50),
+		"only/file.go":  strings.Repeat("x", 10),
+		"empty/a.go":    "",
+		"empty/b.go":    "",
+	})
+
+	rule := newRule(t, Rule{DirSizeRatio: ">0.5"})
+
+	files := walkTemp(t, &Config{})
+	markDirSizeRatios(files)
+
+	expected := map[string]bool{
+		"lib/big.go":    true,
+		"lib/small1.go": false,
+		"lib/small2.go": false,
+		"lib/small3.go": false,
+		"only/file.go":  true,
+		"empty/a.go":    false,
+		"empty/b.go":    false,
+	}
+
+	for path, dominant := range expected {
+		if rule.Pass(findFile(files, path)) != dominant {
+			t.Errorf("%s: expected dominant %v", path, dominant)
+		}
+	}
+
+	if ratio := findFile(files, "lib/small1.go").dirSizeRatio; ratio != 0.1 {
+		t.Errorf("lib/small1.go: expected ratio 0.1, got %v", ratio)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+// errNothingSelected is returned when fzf exits without selection, e.g.
+// because user pressed Escape.
+var errNothingSelected = errors.New("nothing selected")
+
+// selectWithFzf runs fzf over given files and returns path chosen by user,
+// preview command gets path via fzf's {} placeholder which fzf quotes
+// itself; the placeholder is appended if command doesn't contain it.
+func selectWithFzf(files []*File, preview string) (string, error) {
+	args := []string{"--tiebreak=index"}
+
+	if preview != "" {
+		if !strings.Contains(preview, "{}") {
+			preview += " {}"
+		}
+
+		args = append(args, "--preview", preview)
+	}
+
+	input := strings.Builder{}
+	for _, file := range files {
+		input.WriteString(file.Path)
+		input.WriteByte('\n')
+	}
+
+	cmd := exec.Command("fzf", args...)
+	cmd.Stdin = strings.NewReader(input.String())
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// 1 means no match and 130 means fzf was interrupted
+			switch exitErr.ExitCode() {
+			case 1, 130:
+				return "", errNothingSelected
+			}
+		}
+
+		return "", karma.Format(
+			err,
+			"unable to run fzf",
+		)
+	}
+
+	return strings.TrimRight(string(out), "\n"), nil
+}
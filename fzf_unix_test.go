@@ -0,0 +1,101 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubFzf puts fake fzf into PATH, it saves its arguments and input and
+// runs given shell script.
+func stubFzf(t *testing.T, script string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	writeFile(
+		t, filepath.Join(dir, "fzf"),
+		"#!/bin/sh\n"+
+			`printf '%s\n' "$@" > "$(dirname "$0")/args"`+"\n"+
+			`cat > "$(dirname "$0")/input"`+"\n"+
+			script+"\n",
+	)
+
+	err := os.Chmod(filepath.Join(dir, "fzf"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return dir
+}
+
+func TestSelectWithFzf(t *testing.T) {
+	dir := stubFzf(t, `sed -n 2p "$(dirname "$0")/input"`)
+
+	files := []*File{{Path: "a.go"}, {Path: "b b.go"}, {Path: "c.go"}}
+
+	selected, err := selectWithFzf(files, "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if selected != "b b.go" {
+		t.Errorf("expected b b.go to be selected, got %q", selected)
+	}
+
+	input, err := os.ReadFile(filepath.Join(dir, "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(input) != "a.go\nb b.go\nc.go\n" {
+		t.Errorf("unexpected fzf input: %q", input)
+	}
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(args), "--preview\ncat {}\n") {
+		t.Errorf("preview placeholder should be appended, got %q", args)
+	}
+}
+
+func TestSelectWithFzfNothingSelected(t *testing.T) {
+	stubFzf(t, "exit 130")
+
+	_, err := selectWithFzf([]*File{{Path: "a.go"}}, "")
+	if err != errNothingSelected {
+		t.Errorf("expected nothing selected, got %v", err)
+	}
+}
+
+func TestFzfMode(t *testing.T) {
+	stubFzf(t, `head -n 1 "$(dirname "$0")/input"`)
+
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.md": "",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "rules": [{"suffix": ".go", "score": 10}]
+}`)
+
+	stdout, _, err := runProls(t, "-c", config, "--fzf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "b.md\n" {
+		t.Errorf("expected only selected path printed, got %q", stdout)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getCommitTimes returns time of the last commit touching every file under
+// current directory, nothing is returned outside of git repository.
+func getCommitTimes() map[string]time.Time {
+	cmd := exec.Command(
+		"git", "-c", "core.quotepath=off",
+		"log", "--relative", "--name-only", "--format=%x00%ct",
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		if debug {
+			log.Debugf(err, "unable to get commit times")
+		}
+
+		return nil
+	}
+
+	times := map[string]time.Time{}
+
+	var current time.Time
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "\x00"):
+			timestamp, err := strconv.ParseInt(line[1:], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			current = time.Unix(timestamp, 0)
+
+		default:
+			// log goes from newest commits to oldest
+			path := filepath.Clean(line)
+			if _, ok := times[path]; !ok {
+				times[path] = current
+			}
+		}
+	}
+
+	return times
+}
+
+func markCommitTimes(files []*File) {
+	times := getCommitTimes()
+
+	for _, file := range files {
+		if commitTime, ok := times[filepath.Clean(file.Path)]; ok {
+			file.CommitTime = &commitTime
+		}
+	}
+}
+
+// markRepoDepths calculates depth of every file relative to the nearest
+// enclosing git repository, depth matches walk depth if there is no such
+// repository.
+func markRepoDepths(files []*File) {
+	roots := map[string]string{}
+
+	var findRoot func(dir string) string
+	findRoot = func(dir string) string {
+		if root, ok := roots[dir]; ok {
+			return root
+		}
+
+		root := ""
+
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		if err == nil {
+			root = dir
+		} else if parent := filepath.Dir(dir); parent != dir {
+			root = findRoot(parent)
+		}
+
+		roots[dir] = root
+
+		return root
+	}
+
+	for _, file := range files {
+		depth := file.Depth()
+		file.RepoDepth = &depth
+
+		path, err := filepath.Abs(file.Path)
+		if err != nil {
+			continue
+		}
+
+		root := findRoot(filepath.Dir(path))
+		if root == "" {
+			continue
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+
+		depth = strings.Count(filepath.ToSlash(relative), "/") + 1
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(
+		os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_AUTHOR_DATE=2020-01-01T00:00:00Z",
+		"GIT_COMMITTER_DATE=2020-01-01T00:00:00Z",
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %s", args, err, out)
+	}
+}
+
+func TestUncommitted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	chdirTemp(t, map[string]string{
+		"edited.go":   "package main\n",
+		"pristine.go": "package main\n",
+	})
+
+	runGit(t, "init", "-q")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "initial")
+
+	committed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := os.Chtimes("pristine.go", committed, committed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, "edited.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, "untracked.go", "package main\n")
+
+	rule := newRule(t, Rule{Uncommitted: boolPtr(true)})
+
+	files := walkTemp(t, &Config{IgnoreDirs: []string{".git"}})
+	markCommitTimes(files)
+
+	if !rule.Pass(findFile(files, "edited.go")) {
+		t.Errorf("edited.go: file touched after commit should match")
+	}
+
+	for _, path := range []string{"pristine.go", "untracked.go"} {
+		if rule.Pass(findFile(files, path)) {
+			t.Errorf("%s: file should not match", path)
+		}
+	}
+}
+
+func TestUncommittedOutsideRepository(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{"main.go": ""})
+
+	t.Setenv("GIT_CEILING_DIRECTORIES", dir)
+
+	rule := newRule(t, Rule{Uncommitted: boolPtr(true)})
+
+	files := walkTemp(t, &Config{})
+	markCommitTimes(files)
+
+	if rule.Pass(findFile(files, "main.go")) {
+		t.Errorf("main.go: file outside of git repository should not match")
+	}
+}
+
+func TestRepoDepth(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".git/HEAD":                   "",
+		"main.go":                     "",
+		"pkg/lib.go":                  "",
+		"vendor/dep/.git":             "gitdir: ../../.git/modules/dep\n",
+		"vendor/dep/dep.go":           "",
+		"vendor/dep/sub/inner.go":     "",
+		"vendor/dep/nested/.git/HEAD": "",
+		"vendor/dep/nested/deep.go":   "",
+	})
+
+	files := walkTemp(t, &Config{IgnoreDirs: []string{".git"}})
+	markRepoDepths(files)
+
+	expected := map[string]int{
+		"main.go":                   1,
+		"pkg/lib.go":                2,
+		"vendor/dep/dep.go":         1,
+		"vendor/dep/sub/inner.go":   2,
+		"vendor/dep/nested/deep.go": 1,
+	}
+
+	for path, depth := range expected {
+		actual := findFile(files, path).RepoDepth
+		if actual == nil {
+			t.Errorf("%s: repo depth is not calculated", path)
+		} else if *actual != depth {
+			t.Errorf("%s: expected repo depth %d, got %d", path, depth, *actual)
+		}
+	}
+
+	rule := newRule(t, Rule{RepoDepth: "1"})
+	if !rule.Pass(findFile(files, "vendor/dep/dep.go")) {
+		t.Errorf("vendor/dep/dep.go: file in submodule root should match")
+	}
+}
+
+func TestRepoDepthOutsideRepository(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":    "",
+		"pkg/lib.go": "",
+	})
+
+	files := walkTemp(t, &Config{})
+	markRepoDepths(files)
+
+	for _, file := range files {
+		if file.RepoDepth == nil {
+			t.Errorf("%s: repo depth is not calculated", file.Path)
+		} else if *file.RepoDepth != file.Depth() {
+			t.Errorf(
+				"%s: repo depth %d should match walk depth %d outside of repository",
+				file.Path, *file.RepoDepth, file.Depth(),
+			)
+		}
+	}
+}
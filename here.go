@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+func splitDir(path string) []string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." || path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// getPathDistance returns number of steps needed to get from directory a to
+// directory b, both are relative to the walk root.
+func getPathDistance(a []string, b []string) int {
+	common := 0
+	for common < len(a) && common < len(b) && a[common] == b[common] {
+		common++
+	}
+
+	return len(a) - common + len(b) - common
+}
+
+// markHereDistances calculates distance from every file's directory to given
+// directory.
+func markHereDistances(files []*File, here string) error {
+	root, err := os.Getwd()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to get current directory",
+		)
+	}
+
+	here, err = filepath.Abs(here)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to resolve %s", here,
+		)
+	}
+
+	relative, err := filepath.Rel(root, here)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to get path of %s relative to %s", here, root,
+		)
+	}
+
+	hereDir := splitDir(relative)
+
+	for _, file := range files {
+		file.hereDistance = getPathDistance(
+			hereDir,
+			splitDir(filepath.Dir(file.Path)),
+		)
+	}
+
+	return nil
+}
+
+// getPathSimilarity returns share of leading path components which are
+// common for both paths, 1 means paths are equal.
+func getPathSimilarity(a []string, b []string) float64 {
+	common := 0
+	for common < len(a) && common < len(b) && a[common] == b[common] {
+		common++
+	}
+
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+
+	if longest == 0 {
+		return 0
+	}
+
+	return float64(common) / float64(longest)
+}
+
+// applyLikeScore adds to every file given score multiplied by its similarity
+// to the most similar of reference paths.
+func applyLikeScore(files []*File, likes []string, score int) []*File {
+	if len(likes) == 0 || score == 0 {
+		return files
+	}
+
+	references := [][]string{}
+	for _, like := range likes {
+		references = append(references, splitDir(like))
+	}
+
+	for _, file := range files {
+		path := splitDir(file.Path)
+
+		similarity := 0.0
+		for _, reference := range references {
+			value := getPathSimilarity(path, reference)
+			if value > similarity {
+				similarity = value
+			}
+		}
+
+		file.Score += int(float64(score) * similarity)
+	}
+
+	return files
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNearHere(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"src/app/main.go":  "",
+		"src/app/sub/x.go": "",
+		"src/lib/lib.go":   "",
+		"docs/guide.md":    "",
+		"top.go":           "",
+	})
+
+	rules := []Rule{newRule(t, Rule{NearHere: true, Score: 100})}
+
+	files := walkTemp(t, &Config{Rules: rules})
+
+	err := markHereDistances(files, "src/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files = applyRules(context.Background(), files, rules, nil)
+
+	for path, score := range map[string]int{
+		"src/app/main.go":  100,
+		"src/app/sub/x.go": 50,
+		"src/lib/lib.go":   33,
+		"top.go":           33,
+		"docs/guide.md":    25,
+	} {
+		file := findFile(files, path)
+		if file.Score != score {
+			t.Errorf("%s: score = %d, want %d", path, file.Score, score)
+		}
+	}
+
+	files = applySortScore(files, false, false)
+	if files[len(files)-1].Path != "src/app/main.go" {
+		t.Errorf("file in --here directory should be the best, got %v", getPaths(files))
+	}
+}
+
+func TestApplyLikeScore(t *testing.T) {
+	files := []*File{
+		{Path: "api/handlers/user.go"},
+		{Path: "api/handlers/order.go"},
+		{Path: "api/models/user.go"},
+		{Path: "web/src/app/page.tsx"},
+		{Path: "web/src/lib/fetch.ts"},
+		{Path: "docs/README.md"},
+		{Path: "Makefile"},
+	}
+
+	files = applyLikeScore(
+		files,
+		[]string{"api/handlers/auth.go", "web/src/app/layout.tsx"},
+		100,
+	)
+
+	scores := map[string]int{}
+	for _, file := range files {
+		scores[file.Path] = file.Score
+	}
+
+	for _, near := range []string{
+		"api/handlers/user.go", "api/handlers/order.go", "web/src/app/page.tsx",
+	} {
+		for _, unrelated := range []string{"docs/README.md", "Makefile"} {
+			if scores[near] <= scores[unrelated] {
+				t.Errorf(
+					"%s (%d) should outrank %s (%d)",
+					near, scores[near], unrelated, scores[unrelated],
+				)
+			}
+		}
+	}
+
+	if scores["api/handlers/user.go"] <= scores["api/models/user.go"] {
+		t.Errorf("file in the same directory as reference should score higher")
+	}
+
+	if scores["web/src/app/page.tsx"] <= scores["web/src/lib/fetch.ts"] {
+		t.Errorf("file closer to the second reference should score higher")
+	}
+
+	if scores["docs/README.md"] != 0 || scores["Makefile"] != 0 {
+		t.Errorf("unrelated files should not get like score: %v", scores)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/reconquest/karma-go"
+)
+
+// runHook runs given shell command with root of the walk exposed in
+// PROLS_ROOT; hook output goes to stderr to keep list of files clean.
+func runHook(command string) error {
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to resolve root directory",
+		)
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "PROLS_ROOT="+root)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return karma.
+			Describe("command", command).
+			Format(
+				err,
+				"hook failed",
+			)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{
+		"main.go": "package main\n",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{"ignore_dirs": [".git"], "rules": []}`)
+
+	calls := filepath.Join(t.TempDir(), "hooks.log")
+
+	stdout, _, err := runProls(
+		t,
+		"-c", config,
+		"--pre", `echo "pre $PROLS_ROOT" >> `+calls+` && touch created.go`,
+		"--post", `echo post >> `+calls,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stdout, "created.go") {
+		t.Errorf("pre hook should run before walking, got:\n%s", stdout)
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "pre "+dir+"\npost\n" {
+		t.Errorf("unexpected hook calls:\n%s", data)
+	}
+}
+
+func TestHooksFailingPre(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go": "package main\n",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "lister": ["sh", "-c", "touch listed; echo main.go"]
+}`)
+
+	stdout, _, err := runProls(
+		t,
+		"-c", config,
+		"--pre", "exit 1",
+		"--post", "touch post",
+	)
+	if err == nil {
+		t.Errorf("failing pre hook should abort prols")
+	}
+
+	if stdout != "" {
+		t.Errorf("no files should be printed, got:\n%s", stdout)
+	}
+
+	for _, name := range []string{"listed", "post"} {
+		if _, err := os.Stat(name); err == nil {
+			t.Errorf("%s should not be created after failing pre hook", name)
+		}
+	}
+}
+
+func TestRunHookFailure(t *testing.T) {
+	chdirTemp(t, nil)
+
+	if runHook("true") != nil {
+		t.Errorf("successful command should not fail")
+	}
+
+	if runHook("exit 3") == nil {
+		t.Errorf("failing command should be reported")
+	}
+}
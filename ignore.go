@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+// prolsIgnoreName is a name of files with gitignore-like patterns of files
+// which should not be listed, patterns apply to directory of the file.
+const prolsIgnoreName = ".prolsignore"
+
+type IgnorePattern struct {
+	pattern  string
+	base     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreMatcher matches paths against gitignore-style patterns; braces in
+// patterns are expanded only if braces is set, since git treats them
+// literally.
+type IgnoreMatcher struct {
+	patterns []IgnorePattern
+	braces   bool
+}
+
+func (matcher *IgnoreMatcher) Load(name string, base string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to open %s", name,
+		)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		err := matcher.Add(scanner.Text(), base)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid pattern at %s:%d", name, line,
+			)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to read %s", name,
+		)
+	}
+
+	return nil
+}
+
+// LoadDir loads ignore file with given name located in given directory, if
+// any; directories which can't be looked into are treated as having no
+// ignore file.
+func (matcher *IgnoreMatcher) LoadDir(dir string, name string) error {
+	path := filepath.Join(dir, name)
+
+	_, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) && debug {
+			log.Debugf(err, "unable to stat %s, skipping it", path)
+		}
+
+		return nil
+	}
+
+	base := filepath.ToSlash(filepath.Clean(dir))
+	if base == "." {
+		base = ""
+	}
+
+	return matcher.Load(path, base)
+}
+
+// LoadParents loads ignore files from every parent directory of given path
+// up to the root which is not listed in loaded yet.
+func (matcher *IgnoreMatcher) LoadParents(
+	path string,
+	name string,
+	loaded map[string]struct{},
+) error {
+	dirs := []string{"."}
+
+	components := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	for i := 1; i < len(components); i++ {
+		dirs = append(dirs, strings.Join(components[:i], "/"))
+	}
+
+	for _, dir := range dirs {
+		if _, ok := loaded[dir]; ok {
+			continue
+		}
+
+		loaded[dir] = struct{}{}
+
+		err := matcher.LoadDir(dir, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (matcher *IgnoreMatcher) Add(line string, base string) error {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	patterns, err := parseIgnorePattern(line, base, matcher.braces)
+	if err != nil {
+		return err
+	}
+
+	matcher.patterns = append(matcher.patterns, patterns...)
+
+	return nil
+}
+
+// parseIgnorePattern parses single gitignore-style pattern relative to given
+// base directory, several patterns are returned if braces are expanded and
+// pattern contains them.
+func parseIgnorePattern(
+	line string,
+	base string,
+	braces bool,
+) ([]IgnorePattern, error) {
+	pattern := IgnorePattern{
+		base: base,
+	}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if strings.Contains(line, "/") {
+		pattern.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+
+	if line == "" {
+		return nil, nil
+	}
+
+	expanded := []string{line}
+	if braces {
+		var err error
+
+		expanded, err = expandBraces(line)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patterns := []IgnorePattern{}
+	for _, line := range expanded {
+		pattern.pattern = line
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// Match reports whether given slash-separated path is ignored, either by
+// itself or because one of its parent directories is ignored.
+func (matcher *IgnoreMatcher) Match(target string, dir bool) bool {
+	target = filepath.ToSlash(filepath.Clean(target))
+
+	components := strings.Split(target, "/")
+	for i := 1; i < len(components); i++ {
+		if matcher.match(strings.Join(components[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return matcher.match(target, dir)
+}
+
+func (matcher *IgnoreMatcher) match(target string, dir bool) bool {
+	ignored := false
+
+	for _, pattern := range matcher.patterns {
+		if ignored != pattern.negate {
+			continue
+		}
+
+		if pattern.Match(target, dir) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+// MatchPath reports whether pattern matches given file path or any of its
+// parent directories.
+func (pattern IgnorePattern) MatchPath(target string) bool {
+	components := strings.Split(target, "/")
+	for i := 1; i < len(components); i++ {
+		if pattern.Match(strings.Join(components[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return pattern.Match(target, false)
+}
+
+func (pattern IgnorePattern) Match(target string, dir bool) bool {
+	if pattern.dirOnly && !dir {
+		return false
+	}
+
+	if pattern.base != "" {
+		if !strings.HasPrefix(target, pattern.base+"/") {
+			return false
+		}
+
+		target = strings.TrimPrefix(target, pattern.base+"/")
+	}
+
+	if !pattern.anchored {
+		target = path.Base(target)
+	}
+
+	return matchGlob(pattern.pattern, target)
+}
+
+// expandBraces turns pattern like "*.{go,md}" into list of patterns, one per
+// alternative; braces can be nested.
+func expandBraces(pattern string) ([]string, error) {
+	start := -1
+	depth := 0
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+
+			depth++
+
+		case '}':
+			if depth == 0 {
+				return nil, karma.
+					Describe("pattern", pattern).
+					Format(nil, "unexpected closing brace")
+			}
+
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			prefix := pattern[:start]
+			suffix := pattern[i+1:]
+
+			results := []string{}
+			for _, alternative := range splitAlternatives(pattern[start+1 : i]) {
+				expanded, err := expandBraces(prefix + alternative + suffix)
+				if err != nil {
+					return nil, err
+				}
+
+				results = append(results, expanded...)
+			}
+
+			return results, nil
+		}
+	}
+
+	if depth > 0 {
+		return nil, karma.
+			Describe("pattern", pattern).
+			Format(nil, "unclosed brace")
+	}
+
+	return []string{pattern}, nil
+}
+
+func splitAlternatives(list string) []string {
+	alternatives := []string{}
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(alternatives, list[start:])
+}
+
+func matchGlob(pattern string, target string) bool {
+	return matchGlobSegments(
+		strings.Split(pattern, "/"),
+		strings.Split(target, "/"),
+	)
+}
+
+func matchGlobSegments(patterns []string, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			patterns = patterns[1:]
+			if len(patterns) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(names); i++ {
+				if matchGlobSegments(patterns, names[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		matched, err := path.Match(patterns[0], names[0])
+		if err != nil || !matched {
+			return false
+		}
+
+		patterns = patterns[1:]
+		names = names[1:]
+	}
+
+	return len(names) == 0
+}
+
+// loadIgnoreFiles builds matcher out of every ignore file with given name
+// found among files, deeper files take precedence over shallower ones.
+func loadIgnoreFiles(files []*File, name string) (*IgnoreMatcher, error) {
+	sources := []*File{}
+	for _, file := range files {
+		if filepath.Base(file.Path) == name {
+			sources = append(sources, file)
+		}
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Depth() < sources[j].Depth()
+	})
+
+	matcher := &IgnoreMatcher{}
+	for _, source := range sources {
+		base := filepath.ToSlash(filepath.Dir(filepath.Clean(source.Path)))
+		if base == "." {
+			base = ""
+		}
+
+		err := matcher.Load(source.Path, base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matcher, nil
+}
+
+func markGitIgnored(files []*File) error {
+	matcher, err := loadIgnoreFiles(files, ".gitignore")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		ignored := matcher.Match(file.Path, false)
+		file.GitIgnored = &ignored
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMarkGitIgnored(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".gitignore":    "*.log\nbuild/\n",
+		"main.go":       "package main\n",
+		"debug.log":     "debug\n",
+		"build/app":     "binary\n",
+		"docs/.keep":    "",
+		"docs/notes.md": "notes\n",
+	})
+
+	files := walkTemp(t, &Config{})
+
+	err := markGitIgnored(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := newRule(t, Rule{GitIgnored: boolPtr(true), Score: 1})
+
+	for path, ignored := range map[string]bool{
+		"main.go":       false,
+		"debug.log":     true,
+		"build/app":     true,
+		"docs/notes.md": false,
+	} {
+		file := findFile(files, path)
+		if file == nil {
+			t.Fatalf("%s: not found, ignored files should not be dropped", path)
+		}
+
+		if isTrue(file.GitIgnored) != ignored {
+			t.Errorf("%s: gitignored = %v, want %v", path, isTrue(file.GitIgnored), ignored)
+		}
+
+		if rule.Pass(file) != ignored {
+			t.Errorf("%s: rule passed = %v, want %v", path, !ignored, ignored)
+		}
+	}
+}
+
+func TestMarkGitIgnoredBraces(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".gitignore": "*.{c,h}\nfoo{\n",
+		"main.c":     "",
+		"main.h":     "",
+		"x.{c,h}":    "",
+		"foo{":       "",
+	})
+
+	files := walkTemp(t, &Config{})
+
+	err := markGitIgnored(files)
+	if err != nil {
+		t.Fatalf("braces in .gitignore should not fail: %s", err)
+	}
+
+	for path, ignored := range map[string]bool{
+		"main.c":  false,
+		"main.h":  false,
+		"x.{c,h}": true,
+		"foo{":    true,
+	} {
+		if isTrue(findFile(files, path).GitIgnored) != ignored {
+			t.Errorf("%s: gitignored = %v, want %v", path, !ignored, ignored)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	for pattern, expected := range map[string][]string{
+		"*.go":             {"*.go"},
+		"*.{go,md}":        {"*.go", "*.md"},
+		"{a,b}/{c,d}":      {"a/c", "a/d", "b/c", "b/d"},
+		"x.{a,{b,c}}":      {"x.a", "x.b", "x.c"},
+		"file{,.bak}":      {"file", "file.bak"},
+		"empty{}":          {"empty"},
+		`literal\{a,b\}`:   {`literal\{a,b\}`},
+		"go.{mod,sum}":     {"go.mod", "go.sum"},
+		"{src,lib}/**/*.c": {"src/**/*.c", "lib/**/*.c"},
+	} {
+		actual, err := expandBraces(pattern)
+		if err != nil {
+			t.Errorf("%s: %s", pattern, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: got %q, want %q", pattern, actual, expected)
+		}
+	}
+
+	for _, pattern := range []string{"*.{go,md", "*.go}", "{a,{b}", "a}{"} {
+		_, err := expandBraces(pattern)
+		if err == nil {
+			t.Errorf("%s: malformed braces should be reported", pattern)
+		}
+	}
+}
+
+func TestBracesInPatterns(t *testing.T) {
+	matcher := &IgnoreMatcher{braces: true}
+
+	err := matcher.Add("*.{go,md}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ignored := range map[string]bool{
+		"main.go":        true,
+		"docs/README.md": true,
+		"main.c":         false,
+	} {
+		if matcher.Match(path, false) != ignored {
+			t.Errorf("%s: ignored = %v, want %v", path, !ignored, ignored)
+		}
+	}
+
+	err = matcher.Add("*.{go", "")
+	if err == nil {
+		t.Errorf("malformed pattern should be reported")
+	}
+
+	rule := newRule(t, Rule{Except: "{vendor,third_party}/", Score: 1})
+
+	for path, passed := range map[string]bool{
+		"main.go":               true,
+		"vendor/lib/lib.go":     false,
+		"third_party/x/y.go":    false,
+		"internal/vendor.go":    true,
+		"internal/vendor/ok.go": false,
+	} {
+		if rule.Pass(&File{Path: path}) != passed {
+			t.Errorf("%s: except passed = %v, want %v", path, !passed, passed)
+		}
+	}
+}
+
+func TestProlsIgnore(t *testing.T) {
+	tree := map[string]string{
+		".prolsignore":       "*.log\n/build/\n",
+		"main.go":            "",
+		"debug.log":          "",
+		"build/app":          "",
+		"docs/build/page.md": "",
+		"docs/trace.log":     "",
+		"docs/.prolsignore":  "draft-*.md\n!draft-keep.md\n",
+		"docs/draft-a.md":    "",
+		"docs/draft-keep.md": "",
+		"draft-b.md":         "",
+	}
+
+	expected := []string{
+		".prolsignore",
+		"docs/.prolsignore",
+		"docs/build/page.md",
+		"docs/draft-keep.md",
+		"draft-b.md",
+		"main.go",
+	}
+
+	chdirTemp(t, tree)
+
+	lister := []string{"sh", "-c", "find . -type f | sed 's#^./##' | sort"}
+
+	for name, config := range map[string]*Config{
+		"walk":   {},
+		"lister": {Lister: lister},
+	} {
+		files := walkTemp(t, config)
+
+		paths := getPaths(files)
+		sort.Strings(paths)
+
+		if !reflect.DeepEqual(paths, expected) {
+			t.Errorf("%s: got %v, want %v", name, paths, expected)
+		}
+	}
+}
+
+func TestProlsIgnoreUnreadableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	chdirTemp(t, map[string]string{
+		"main.go":           "",
+		"locked/inner/file": "",
+	})
+
+	err := os.Chmod("locked", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chmod("locked", 0755)
+
+	files := walkTemp(t, &Config{})
+	if findFile(files, "main.go") == nil {
+		t.Errorf("unreadable directory should not abort walk, got %v", getPaths(files))
+	}
+}
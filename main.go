@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/kovetskiy/lorg"
@@ -21,13 +26,93 @@ var (
 Flexible project-wide search tool based on rules and scores.
 
 Usage:
-  prols [options]
+  prols [options] [--like <path>]...
+  prols [options] --merge <file>...
   prols -h | --help
   prols --version
 
 Options:
   -c --global <path>  Use specified global prols file.
                        [default: $HOME/.config/prols/prols.conf]
+  --benchmark-rules   Print time spent evaluating every rule instead of
+                       list of files.
+  --json              Print files with their scores as JSON.
+  --jsonl             Print every file with its score as JSON object on
+                       separate line.
+  --merge             Merge results of previous --json runs instead of
+                       walking directory.
+  --merge-strategy <strategy>  Combine scores of files listed in several
+                       merged results using sum or max. [default: sum]
+  --ignore-case-output  Treat paths which differ only in case as the same
+                       file, the first seen spelling is printed.
+  --print-config-hash  Print hash of resolved configuration and exit.
+  --pre <cmd>         Run shell command before walking directory, abort if
+                       it fails.
+  --post <cmd>        Run shell command after printing files.
+  --dry-run           Walk directory and print how many files and content
+                       reads would be processed without reading files.
+  --progress          Report number of processed files to stderr if it's
+                       a terminal.
+  --here <dir>        Directory used by near_here rules. [default: .]
+  --top-per-extension <n>  Print only given number of best files for every
+                       file extension.
+  --explain-threshold <delta>  Print to stderr rules which contributed to
+                       score of files which score is within given distance
+                       from zero, which is cutoff of hide_negative.
+  --select-random-weighted  Print single random file, files with higher
+                       score are picked more often.
+  --seed <seed>       Seed for --select-random-weighted.
+  --session-start <time>  Time when editing session started, as unix
+                       timestamp or RFC3339, used by session_modified rules;
+                       PROLS_SESSION_START is used if not specified.
+  --bucketed          Group files under headers of score buckets configured
+                       with score_buckets.
+  --config-diff-explain <path>  Print files which position changes when
+                       using given config instead of global one along with
+                       rules which contributed differently to their score,
+                       sizes snapshot of size_changed is not updated.
+  --unstable-sort     Order files with equal score by path using faster
+                       unstable sort, presort is not taken into account.
+  --reverse-ties      Reverse order of files with equal score only, unlike
+                       reverse option which reverses whole output.
+  --export-features <path>  Write features of every file, such as depth,
+                       size and rules it passed, to given file as CSV, or
+                       as JSON lines if path ends with .jsonl.
+  --head-rules <n>    Apply only given number of first rules.
+  --rules-range <a:b>  Apply only rules from a-th to b-th inclusive, counting
+                       from one; either side can be omitted.
+  --results-cache <path>  Save scored files to given file.
+  --use-cache         Use files saved with --results-cache instead of
+                       walking directory if no files have changed; it is
+                       not used by diagnostic modes like --dry-run, with
+                       replayed walk and with rules depending on time or
+                       files outside of directory, like dir_recency.
+  --normalize-paths <form>  Print paths in given unicode normalization form,
+                       NFC or NFD.
+  --deadline <duration>  Stop walking and scoring files after given time,
+                       like 500ms, and print files scored so far; if it is
+                       exceeded before scoring, files found so far are
+                       printed unscored.
+  --printf <format>   Print every file using given format, where %p is
+                       replaced with path, %s with score, %d with depth, %z
+                       with size and %% with percent sign.
+  --like <path>       Boost files similar to given path by like_score,
+                       can be specified several times.
+  --fzf               Pick file using fzf and print only selected path.
+  --fzf-preview <cmd>  Command which fzf uses to preview highlighted file,
+                       {} is replaced with quoted path.
+  --min-files <n>     Exit with error if less than given number of files
+                       would be printed.
+  --record-walk <path>  Save list of found files along with their metadata
+                       to given file.
+  --replay-walk <path>  Score files saved with --record-walk instead of
+                       walking directory; content rules still read files.
+  --append-scores-to <path>  Add scores of files to scores accumulated over
+                       previous runs in given file, which are used by
+                       accumulated_score rules.
+  --accum-decay <decay>  Keep exponential moving average of scores instead
+                       of the sum, giving previous value given weight
+                       between 0 and 1.
   --debug             Print debug messages.
   -h --help           Show this screen.
   --version           Show version.
@@ -36,8 +121,9 @@ Options:
 )
 
 var (
-	log   *cog.Logger
-	debug bool
+	log      *cog.Logger
+	debug    bool
+	progress *Progress
 )
 
 func initLogger(args map[string]interface{}) {
@@ -74,14 +160,258 @@ func main() {
 		)
 	}
 
-	files, err := walk(config)
+	config.Rules, err = selectRules(config.Rules, args)
 	if err != nil {
-		log.Fatalf(err, "unable to walk directory")
+		log.Fatalf(err, "invalid rules selection")
 	}
 
-	files = applyPreSort(files, config.PreSort)
-	files = applyRules(files, config.Rules)
-	files = applySortScore(files)
+	if args["--print-config-hash"].(bool) {
+		hash, err := config.Hash()
+		if err != nil {
+			log.Fatalf(err, "unable to calculate config hash")
+		}
+
+		fmt.Println(hash)
+		return
+	}
+
+	if path, ok := args["--config-diff-explain"].(string); ok {
+		err := explainConfigDiff(os.Stdout, config, path, args)
+		if err != nil {
+			log.Fatalf(err, "unable to compare configs")
+		}
+
+		return
+	}
+
+	if command, ok := args["--pre"].(string); ok {
+		err := runHook(command)
+		if err != nil {
+			log.Fatalf(err, "unable to run pre hook")
+		}
+	}
+
+	if args["--progress"].(bool) {
+		progress = startProgress(os.Stderr)
+	}
+
+	cachePath, caching := args["--results-cache"].(string)
+
+	// replayed walk doesn't describe directory tree and some rules depend
+	// on time or files outside of it, so staleness of such cache can't be
+	// detected
+	if caching && !isResultsCacheable(config, args) {
+		if debug {
+			log.Debugf(nil, "results cache is not used")
+		}
+
+		caching = false
+	}
+
+	var cacheKey string
+	if caching {
+		cacheKey, err = getResultsCacheKey(config, args)
+		if err != nil {
+			log.Fatalf(err, "unable to calculate results cache key")
+		}
+	}
+
+	ctx := context.Background()
+
+	if value, ok := args["--deadline"].(string); ok {
+		deadline, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf(err, "invalid --deadline value: %s", value)
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	var files []*File
+	var cached bool
+
+	// accumulated scores change after every run, so cached scores are stale;
+	// diagnostic modes need walk and rule contributions which cache lacks
+	if caching && args["--use-cache"].(bool) && !args["--merge"].(bool) &&
+		args["--append-scores-to"] == nil && !args["--dry-run"].(bool) &&
+		!args["--benchmark-rules"].(bool) &&
+		args["--export-features"] == nil &&
+		args["--record-walk"] == nil &&
+		args["--explain-threshold"] == nil {
+		files, cached = loadResultsCache(cachePath, cacheKey)
+		if debug {
+			log.Debugf(nil, "results cache hit: %v", cached)
+		}
+	}
+
+	switch {
+	case cached:
+		// files are already scored, no need to touch file system
+
+	case args["--merge"].(bool):
+		files, err = mergeFiles(
+			args["<file>"].([]string),
+			args["--merge-strategy"].(string),
+			args["--ignore-case-output"].(bool),
+		)
+		if err != nil {
+			log.Fatalf(err, "unable to merge results")
+		}
+
+	default:
+		var dirs []string
+
+		if path, ok := args["--replay-walk"].(string); ok {
+			files, err = replayWalk(path)
+			if err != nil {
+				log.Fatalf(err, "unable to replay walk")
+			}
+		} else {
+			files, dirs, err = walk(ctx, config, args["--dry-run"].(bool))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Fatalf(err, "unable to walk directory")
+				}
+
+				log.Warningf(err, "deadline exceeded, results are partial")
+			}
+		}
+
+		if path, ok := args["--record-walk"].(string); ok {
+			err := recordWalk(path, files)
+			if err != nil {
+				log.Fatalf(err, "unable to record walk")
+			}
+		}
+
+		if args["--ignore-case-output"].(bool) {
+			files = uniqueFiles(files, true)
+		}
+
+		if config.NormalizePaths != "" {
+			normalizeMatchPaths(files, config.normalizeForm)
+		}
+
+		if args["--dry-run"].(bool) {
+			progress.Stop()
+			printDryRun(os.Stdout, files, config.Rules)
+			return
+		}
+
+		sessionStart, err := getSessionStart(args)
+		if err != nil {
+			log.Fatalf(err, "invalid session start")
+		}
+
+		err = markFiles(
+			ctx,
+			files,
+			config.Rules,
+			args["--here"].(string),
+			sessionStart,
+		)
+		if err != nil && ctx.Err() == nil {
+			log.Fatalf(err, "unable to prepare files")
+		}
+
+		// partial walk would drop sizes of files which were not found in time
+		if ctx.Err() == nil &&
+			hasRule(config.Rules, func(rule Rule) bool { return rule.SizeChanged != "" }) {
+			err = saveSizes(files)
+			if err != nil {
+				log.Fatalf(err, "unable to update sizes snapshot")
+			}
+		}
+
+		accumulatedPath, accumulating := args["--append-scores-to"].(string)
+
+		var accumulated AccumulatedScores
+		var decay float64
+		if accumulating {
+			decay, err = parseAccumDecay(args)
+			if err != nil {
+				log.Fatalf(err, "unable to accumulate scores")
+			}
+
+			accumulated, err = loadAccumulatedScores(accumulatedPath)
+			if err != nil {
+				log.Fatalf(err, "unable to load accumulated scores")
+			}
+
+			markAccumulatedScores(files, accumulated)
+		}
+
+		if !args["--unstable-sort"].(bool) {
+			files = applyPreSort(files, config.PreSort)
+		}
+
+		var benchmarks []RuleBenchmark
+		if args["--benchmark-rules"].(bool) {
+			benchmarks = newRuleBenchmarks(config.Rules)
+		}
+
+		// files found before deadline are printed unscored rather than not
+		// printed at all
+		if ctx.Err() == nil {
+			files = applyRules(ctx, files, config.Rules, benchmarks)
+		} else {
+			log.Warningf(ctx.Err(), "deadline exceeded, files are not scored")
+		}
+		files = applyRootBoost(files, config.RootBoost)
+
+		likes, _ := args["--like"].([]string)
+		files = applyLikeScore(files, likes, config.LikeScore)
+
+		// partial scores would skew accumulated ones
+		if accumulating && ctx.Err() == nil {
+			err := saveAccumulatedScores(
+				accumulatedPath, accumulated, files, decay,
+			)
+			if err != nil {
+				log.Fatalf(err, "unable to save accumulated scores")
+			}
+		}
+
+		// partial results should not be served from cache later
+		if caching && ctx.Err() == nil {
+			err := saveResultsCache(cachePath, cacheKey, files, dirs)
+			if err != nil {
+				log.Warningf(err, "unable to save results cache")
+			}
+		}
+
+		if path, ok := args["--export-features"].(string); ok {
+			err := exportFeatures(path, files, config.Rules)
+			if err != nil {
+				log.Fatalf(err, "unable to export features")
+			}
+		}
+
+		if benchmarks != nil {
+			progress.Stop()
+			printRuleBenchmarks(os.Stdout, benchmarks)
+			return
+		}
+	}
+
+	progress.Stop()
+
+	files = applySortScore(
+		files,
+		args["--unstable-sort"].(bool),
+		args["--reverse-ties"].(bool),
+	)
+
+	if value, ok := args["--top-per-extension"].(string); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			log.Fatalf(err, "invalid --top-per-extension value: %s", value)
+		}
+
+		files = applyTopPerExtension(files, limit)
+	}
 
 	if debug {
 		for _, file := range files {
@@ -90,44 +420,160 @@ func main() {
 	}
 
 	if config.Reverse {
-		for i := len(files)/2 - 1; i >= 0; i-- {
-			opp := len(files) - 1 - i
-			files[i], files[opp] = files[opp], files[i]
+		files = reverseFiles(files)
+	}
+
+	if value, ok := args["--explain-threshold"].(string); ok {
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 {
+			log.Fatalf(err, "invalid --explain-threshold value: %s", value)
 		}
+
+		explainThreshold(os.Stderr, files, threshold)
 	}
 
-	for _, file := range files {
-		if config.HideNegative && file.Score < 0 {
-			continue
+	if config.HideNegative {
+		visible := []*File{}
+		for _, file := range files {
+			if file.Score >= scoreCutoff {
+				visible = append(visible, file)
+			}
+		}
+
+		files = visible
+	}
+
+	if value, ok := args["--min-files"].(string); ok {
+		minimum, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf(err, "invalid --min-files value: %s", value)
+		}
+
+		if len(files) < minimum {
+			log.Fatalf(
+				nil,
+				"only %d files found, at least %d expected",
+				len(files), minimum,
+			)
+		}
+	}
+
+	if args["--select-random-weighted"].(bool) {
+		seed := time.Now().UnixNano()
+		if value, ok := args["--seed"].(string); ok {
+			seed, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				log.Fatalf(err, "invalid --seed value: %s", value)
+			}
+		}
+
+		selected := selectWeighted(files, rand.New(rand.NewSource(seed)))
+
+		files = []*File{}
+		if selected != nil {
+			files = append(files, selected)
+		}
+	}
+
+	if name, ok := args["--normalize-paths"].(string); ok {
+		form, err := parseNormalizationForm(name)
+		if err != nil {
+			log.Fatalf(err, "invalid --normalize-paths value")
+		}
+
+		for _, file := range files {
+			file.Path = form.String(file.Path)
+		}
+	}
+
+	var printf *Printf
+	if format, ok := args["--printf"].(string); ok {
+		printf, err = parsePrintf(format)
+		if err != nil {
+			log.Fatalf(err, "invalid --printf value")
+		}
+	}
+
+	if args["--fzf"].(bool) {
+		preview, _ := args["--fzf-preview"].(string)
+
+		selected, err := selectWithFzf(files, preview)
+		if err != nil {
+			if err == errNothingSelected {
+				os.Exit(1)
+			}
+
+			log.Fatalf(err, "unable to select file")
 		}
 
-		fmt.Println(file.Path)
+		chosen := []*File{}
+		for _, file := range files {
+			if file.Path == selected {
+				chosen = append(chosen, file)
+				break
+			}
+		}
+
+		files = chosen
+	}
+
+	switch {
+	case printf != nil:
+		err = printFormatted(os.Stdout, files, printf)
+	case args["--jsonl"].(bool):
+		err = printJSONLines(os.Stdout, files)
+	case args["--bucketed"].(bool):
+		err = printBucketed(os.Stdout, files, config.ScoreBuckets)
+	default:
+		err = printFiles(os.Stdout, files, args["--json"].(bool))
+	}
+	if err != nil {
+		log.Fatalf(err, "unable to print files")
+	}
+
+	if command, ok := args["--post"].(string); ok {
+		err := runHook(command)
+		if err != nil {
+			log.Warningf(err, "post hook failed")
+		}
 	}
 }
 
-func walk(config *Config) ([]*File, error) {
+// walk lists files according to config along with directories looked into,
+// which are known only if directory is walked rather than listed; files
+// found so far are returned along with context error if context is done
+// before walk is complete.
+func walk(
+	ctx context.Context,
+	config *Config,
+	dryRun bool,
+) ([]*File, []string, error) {
 	ignoreDirs := map[string]struct{}{}
 	for _, path := range config.IgnoreDirs {
 		ignoreDirs[path] = struct{}{}
 	}
 
-	shouldDetectType := false
-	for _, rule := range config.Rules {
-		if rule.Binary != nil {
-			shouldDetectType = true
-			break
-		}
-	}
+	shouldDetectType := !dryRun && hasRule(config.Rules, func(rule Rule) bool {
+		return rule.Binary != nil
+	})
 
-	create := func(path string) (*File, error) {
+	create := func(path string, info os.FileInfo) (*File, error) {
 		file := &File{
-			Path: path,
+			Path:    path,
+			Sparse:  isSparse(info),
+			Links:   getLinks(info),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
 		}
 
+		progress.Discover()
+
 		if shouldDetectType {
 			contentType, err := detectType(".", path)
 			if err != nil {
-				return nil, err
+				log.Warningf(err, "unable to detect type of %s", path)
+				file.fail(err)
 			}
 
 			if contentType == "application/octet-stream" {
@@ -139,6 +585,9 @@ func walk(config *Config) ([]*File, error) {
 	}
 
 	files := []*File{}
+	dirs := []string{}
+
+	ignore := &IgnoreMatcher{braces: true}
 
 	if len(config.Lister) > 0 {
 		args := []string{}
@@ -146,10 +595,20 @@ func walk(config *Config) ([]*File, error) {
 			args = config.Lister[1:]
 		}
 
-		cmd := exec.Command(config.Lister[0], args...)
-		out, err := cmd.Output()
+		cmd := exec.CommandContext(ctx, config.Lister[0], args...)
+		cmd.Stderr = os.Stderr
+
+		stdout, err := cmd.StdoutPipe()
 		if err != nil {
-			return nil, karma.
+			return nil, nil, karma.Format(
+				err,
+				"unable to get lister output",
+			)
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			return nil, nil, karma.
 				Describe("lister", config.Lister).
 				Format(
 					err,
@@ -157,10 +616,49 @@ func walk(config *Config) ([]*File, error) {
 				)
 		}
 
-		paths := strings.Split(strings.TrimSpace(string(out)), "\n")
+		// paths are processed as soon as lister prints them, so files
+		// listed before deadline are kept
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				select {
+				case lines <- scanner.Text():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		loaded := map[string]struct{}{}
 
 	pathsLoop:
-		for _, path := range paths {
+		for {
+			var line string
+			select {
+			case <-ctx.Done():
+				// lister's children may still hold its output open, so
+				// it is not read until the end; waiting for the lister
+				// itself closes the output
+				cmd.Wait()
+
+				return files, dirs, ctx.Err()
+
+			case next, ok := <-lines:
+				if !ok {
+					break pathsLoop
+				}
+
+				line = next
+			}
+
+			path := strings.TrimSpace(line)
+			if path == "" {
+				continue
+			}
+
 			components := filepath.SplitList(path)
 			if len(components) > 1 {
 				for _, dir := range components[:len(components)-1] {
@@ -170,6 +668,24 @@ func walk(config *Config) ([]*File, error) {
 				}
 			}
 
+			if config.SkipHidden {
+				dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+				for _, dir := range dirs {
+					if isHiddenDir(dir) {
+						continue pathsLoop
+					}
+				}
+			}
+
+			err := ignore.LoadParents(path, prolsIgnoreName, loaded)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if ignore.Match(path, false) {
+				continue
+			}
+
 			info, err := os.Stat(path)
 			if err != nil {
 				continue
@@ -179,16 +695,35 @@ func walk(config *Config) ([]*File, error) {
 				continue
 			}
 
-			file, err := create(path)
+			file, err := create(path, info)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			files = append(files, file)
 		}
+
+		err = cmd.Wait()
+		if ctx.Err() != nil {
+			return files, dirs, ctx.Err()
+		}
+
+		if err != nil {
+			return nil, nil, karma.
+				Describe("lister", config.Lister).
+				Format(
+					err,
+					"unable to run external lister",
+				)
+		}
 	} else {
 		walk := func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			if path == "." {
+				dirs = append(dirs, path)
 				return nil
 			}
 
@@ -197,14 +732,28 @@ func walk(config *Config) ([]*File, error) {
 					return filepath.SkipDir
 				}
 
-				return nil
+				if config.SkipHidden && isHiddenDir(info.Name()) {
+					return filepath.SkipDir
+				}
+
+				if ignore.Match(path, true) {
+					return filepath.SkipDir
+				}
+
+				dirs = append(dirs, path)
+
+				return ignore.LoadDir(path, prolsIgnoreName)
 			}
 
 			if !info.Mode().IsRegular() {
 				return nil
 			}
 
-			file, err := create(path)
+			if ignore.Match(path, false) {
+				return nil
+			}
+
+			file, err := create(path, info)
 			if err != nil {
 				return err
 			}
@@ -214,13 +763,213 @@ func walk(config *Config) ([]*File, error) {
 			return nil
 		}
 
-		err := filepath.Walk(".", walk)
+		err := ignore.LoadDir(".", prolsIgnoreName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = filepath.Walk(".", walk)
+		if ctx.Err() != nil {
+			return files, dirs, ctx.Err()
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return files, dirs, nil
+}
+
+// isHiddenDir reports whether directory with given name is hidden, i.e. its
+// name starts with dot.
+func isHiddenDir(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// getSessionStart returns time when editing session started, which is taken
+// from --session-start or PROLS_SESSION_START, either unix timestamp or
+// RFC3339 time is accepted.
+func getSessionStart(args map[string]interface{}) (time.Time, error) {
+	value, ok := args["--session-start"].(string)
+	if !ok {
+		value = os.Getenv("PROLS_SESSION_START")
+	}
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return time.Unix(timestamp, 0), nil
+	}
+
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, karma.Format(
+			err,
+			"unable to parse %s as unix timestamp or RFC3339 time", value,
+		)
+	}
+
+	return start, nil
+}
+
+// selectRules returns rules chosen via --head-rules and --rules-range.
+func selectRules(rules []Rule, args map[string]interface{}) ([]Rule, error) {
+	if value, ok := args["--head-rules"].(string); ok {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return nil, karma.Format(
+				err,
+				"invalid --head-rules value: %s", value,
+			)
+		}
+
+		if count < len(rules) {
+			rules = rules[:count]
+		}
+	}
+
+	if value, ok := args["--rules-range"].(string); ok {
+		bounds := strings.SplitN(value, ":", 2)
+		if len(bounds) != 2 {
+			return nil, karma.Format(
+				nil,
+				"invalid --rules-range value: %s, expected a:b", value,
+			)
+		}
+
+		start, end := 1, len(rules)
+
+		var err error
+		if bounds[0] != "" {
+			start, err = strconv.Atoi(bounds[0])
+		}
+
+		if err == nil && bounds[1] != "" {
+			end, err = strconv.Atoi(bounds[1])
+		}
+
+		if err != nil || start < 1 || end < start-1 {
+			return nil, karma.Format(
+				err,
+				"invalid --rules-range value: %s", value,
+			)
+		}
+
+		if end > len(rules) {
+			end = len(rules)
+		}
+
+		if start > end {
+			return []Rule{}, nil
+		}
+
+		rules = rules[start-1 : end]
+	}
+
+	return rules, nil
+}
+
+// markFiles calculates file properties which require looking at other files
+// or reading additional files, only properties used by rules are calculated;
+// once context is done remaining properties are not calculated.
+func markFiles(
+	ctx context.Context,
+	files []*File,
+	rules []Rule,
+	here string,
+	sessionStart time.Time,
+) error {
+	used := func(match func(Rule) bool) bool {
+		return ctx.Err() == nil && hasRule(rules, match)
+	}
+
+	if used(func(rule Rule) bool { return rule.GitIgnored != nil }) {
+		err := markGitIgnored(files)
+		if err != nil {
+			return karma.Format(err, "unable to read .gitignore files")
+		}
+	}
+
+	if used(func(rule Rule) bool { return rule.Owner != "" }) {
+		err := markOwners(files)
 		if err != nil {
-			return nil, err
+			return karma.Format(err, "unable to read CODEOWNERS")
+		}
+	}
+
+	if used(func(rule Rule) bool { return rule.Errored != nil }) {
+		for _, file := range files {
+			err := probeContent(file.Path)
+			if err != nil {
+				file.fail(err)
+			}
 		}
 	}
 
-	return files, nil
+	if used(func(rule Rule) bool { return rule.IsContentDuplicate != nil }) {
+		markContentDuplicates(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.SizePercentile != "" }) {
+		markSizePercentiles(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.SizeChanged != "" }) {
+		err := markPreviousSizes(files)
+		if err != nil {
+			return karma.Format(err, "unable to read sizes snapshot")
+		}
+	}
+
+	if used(func(rule Rule) bool { return rule.RepoDepth != "" }) {
+		markRepoDepths(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.Uncommitted != nil }) {
+		markCommitTimes(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.InBuildManifest != nil }) {
+		markInBuildManifest(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.SessionModified != nil }) {
+		markSessionModified(files, sessionStart)
+	}
+
+	if used(func(rule Rule) bool { return rule.PositionInDir != "" }) {
+		markDirPositions(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.DirRecency != "" }) {
+		markDirModTimes(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.DirSizeRatio != "" }) {
+		markDirSizeRatios(files)
+	}
+
+	if used(func(rule Rule) bool { return rule.NearHere }) {
+		err := markHereDistances(files, here)
+		if err != nil {
+			return karma.Format(err, "unable to calculate distances")
+		}
+	}
+
+	return ctx.Err()
+}
+
+func reverseFiles(files []*File) []*File {
+	for i := len(files)/2 - 1; i >= 0; i-- {
+		opp := len(files) - 1 - i
+		files[i], files[opp] = files[opp], files[i]
+	}
+
+	return files
 }
 
 func applyPreSort(files []*File, presorts []PreSort) []*File {
@@ -259,24 +1008,132 @@ func applyPreSort(files []*File, presorts []PreSort) []*File {
 	return files
 }
 
-func applySortScore(files []*File) []*File {
+// applySortScore orders files by score, files with equal score keep their
+// order unless reverseTies is set, in which case their order is reversed.
+func applySortScore(files []*File, unstable bool, reverseTies bool) []*File {
+	if unstable {
+		// order of files with equal score is defined by path here, so
+		// stability and therefore presort are not needed
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].Score != files[j].Score {
+				return files[i].Score < files[j].Score
+			}
+
+			return (files[i].Path < files[j].Path) != reverseTies
+		})
+
+		return files
+	}
+
+	if reverseTies {
+		files = reverseFiles(files)
+	}
+
 	sort.SliceStable(files, func(i, j int) bool {
 		return files[i].Score < files[j].Score
 	})
 	return files
 }
 
-func applyRules(files []*File, rules []Rule) []*File {
+// applyTopPerExtension keeps only given number of best files for every
+// extension, extensions are ordered by score of their best file.
+func applyTopPerExtension(files []*File, limit int) []*File {
+	groups := map[string][]*File{}
+	extensions := []string{}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		extension := filepath.Ext(files[i].Path)
+
+		group, ok := groups[extension]
+		if !ok {
+			extensions = append(extensions, extension)
+		}
+
+		if len(group) < limit {
+			groups[extension] = append(group, files[i])
+		}
+	}
+
+	result := []*File{}
+	for i := len(extensions) - 1; i >= 0; i-- {
+		group := groups[extensions[i]]
+		for j := len(group) - 1; j >= 0; j-- {
+			result = append(result, group[j])
+		}
+	}
+
+	return result
+}
+
+// applyRootBoost adds given score to files located directly in the walked
+// directory.
+func applyRootBoost(files []*File, boost int) []*File {
+	if boost == 0 {
+		return files
+	}
+
 	for _, file := range files {
-		for _, rule := range rules {
-			if rule.Pass(file) {
+		if file.Depth() == 1 {
+			file.Score += boost
+		}
+	}
+
+	return files
+}
+
+// applyRules scores files, if context is done only files scored so far are
+// returned.
+func applyRules(
+	ctx context.Context,
+	files []*File,
+	rules []Rule,
+	benchmarks []RuleBenchmark,
+) []*File {
+	for index, file := range files {
+		if ctx.Err() != nil {
+			log.Warningf(
+				ctx.Err(),
+				"deadline exceeded, scored only %d of %d files",
+				index, len(files),
+			)
+
+			return files[:index]
+		}
+
+		for i, rule := range rules {
+			var started time.Time
+			if benchmarks != nil {
+				started = time.Now()
+			}
+
+			passed := rule.Pass(file)
+
+			if benchmarks != nil {
+				benchmarks[i].Total += time.Since(started)
+				benchmarks[i].Files++
+				if passed {
+					benchmarks[i].Matches++
+				}
+			}
+
+			if passed {
 				if debug {
 					log.Debugf(nil, "%s passed %s", file.Path, rule)
 				}
 
-				file.Score += rule.Score
+				delta := rule.Delta(file)
+
+				file.Score += delta
+				file.contributions = append(
+					file.contributions,
+					Contribution{Index: i, Rule: rule, Delta: delta},
+				)
 			}
 		}
+
+		file.releaseContent()
+
+		progress.Score()
 	}
 
 	return files
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docopt/docopt-go"
+)
+
+func TestMain(m *testing.M) {
+	// test binary acts as prols itself when run by runProls
+	if value := os.Getenv("PROLS_TEST_ARGS"); value != "" {
+		var args []string
+
+		err := json.Unmarshal([]byte(value), &args)
+		if err != nil {
+			panic(err)
+		}
+
+		os.Args = append([]string{"prols"}, args...)
+		main()
+		os.Exit(0)
+	}
+
+	initLogger(map[string]interface{}{"--debug": false})
+
+	os.Exit(m.Run())
+}
+
+// chdirTemp changes working directory to new temporary directory with given
+// files, working directory is restored when test is done.
+func chdirTemp(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for path, contents := range files {
+		writeFile(t, filepath.Join(dir, path), contents)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+
+	return dir
+}
+
+// runProls runs prols with given arguments in working directory and returns
+// its stdout and stderr, error is returned if prols exits with non-zero code.
+func runProls(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "PROLS_TEST_ARGS="+string(encoded))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if _, ok := err.(*exec.ExitError); !ok && err != nil {
+		t.Fatal(err)
+	}
+
+	return stdout.String(), stderr.String(), err
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func walkTemp(t *testing.T, config *Config) []*File {
+	t.Helper()
+
+	files, _, err := walk(context.Background(), config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func findFile(files []*File, path string) *File {
+	for _, file := range files {
+		if file.Path == path {
+			return file
+		}
+	}
+
+	return nil
+}
+
+func getPaths(files []*File) []string {
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	return paths
+}
+
+func parseArgs(t *testing.T, argv ...string) map[string]interface{} {
+	t.Helper()
+
+	// nil argv makes docopt parse arguments of the test binary itself
+	if argv == nil {
+		argv = []string{}
+	}
+
+	args, err := docopt.Parse(usage, argv, true, version, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return args
+}
+
+func newRule(t *testing.T, rule Rule) Rule {
+	t.Helper()
+
+	err := rule.init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rule
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestApplyTopPerExtension(t *testing.T) {
+	files := applySortScore([]*File{
+		{Path: "a.go", Score: 10},
+		{Path: "b.go", Score: 30},
+		{Path: "c.go", Score: 20},
+		{Path: "a.md", Score: 5},
+		{Path: "b.md", Score: 40},
+		{Path: "c.md", Score: 1},
+		{Path: "Makefile", Score: 2},
+	}, false, false)
+
+	files = applyTopPerExtension(files, 2)
+
+	// groups are ordered by score of their best file, best files go last
+	// the same way as without the limit
+	expected := []string{"Makefile", "c.go", "b.go", "a.md", "b.md"}
+	if !reflect.DeepEqual(getPaths(files), expected) {
+		t.Errorf("got %v, want %v", getPaths(files), expected)
+	}
+}
+
+func newSortFixture(count int) []*File {
+	random := rand.New(rand.NewSource(1))
+
+	files := make([]*File, count)
+	for i := range files {
+		files[i] = &File{
+			Path:  fmt.Sprintf("dir%d/file%d.go", random.Intn(100), i),
+			Score: random.Intn(50),
+		}
+	}
+
+	return files
+}
+
+func TestApplySortScoreUnstable(t *testing.T) {
+	for _, reverseTies := range []bool{false, true} {
+		stable := newSortFixture(1000)
+		unstable := append([]*File{}, stable...)
+
+		// presort by path makes order of stable sort total as well
+		sort.SliceStable(stable, func(i, j int) bool {
+			return stable[i].Path < stable[j].Path
+		})
+
+		stable = applySortScore(stable, false, reverseTies)
+		unstable = applySortScore(unstable, true, reverseTies)
+
+		if !reflect.DeepEqual(getPaths(stable), getPaths(unstable)) {
+			t.Errorf(
+				"reverse ties %v: stable and unstable sort results differ",
+				reverseTies,
+			)
+		}
+	}
+}
+
+func BenchmarkApplySortScore(b *testing.B) {
+	fixture := newSortFixture(100000)
+
+	for _, unstable := range []bool{false, true} {
+		name := "stable"
+		if unstable {
+			name = "unstable"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				files := append([]*File{}, fixture...)
+				b.StartTimer()
+
+				applySortScore(files, unstable, false)
+			}
+		})
+	}
+}
+
+func TestSelectRules(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":     "package main\n",
+		"README.md":   "",
+		"docs/doc.md": "",
+	})
+
+	rules := []Rule{
+		newRule(t, Rule{Suffix: ".go", Score: 10}),
+		newRule(t, Rule{Suffix: ".md", Score: 5}),
+		newRule(t, Rule{Prefix: "docs/", Score: -20}),
+		newRule(t, Rule{Score: 1}),
+	}
+
+	score := func(rules []Rule) map[string]int {
+		files := walkTemp(t, &Config{Rules: rules})
+		files = applyRules(context.Background(), files, rules, nil)
+
+		scores := map[string]int{}
+		for _, file := range files {
+			scores[file.Path] = file.Score
+		}
+
+		return scores
+	}
+
+	tests := []struct {
+		argv     []string
+		expected []Rule
+	}{
+		{[]string{"--head-rules", "2"}, rules[:2]},
+		{[]string{"--head-rules", "10"}, rules},
+		{[]string{"--head-rules", "0"}, rules[:0]},
+		{[]string{"--rules-range", "2:3"}, rules[1:3]},
+		{[]string{"--rules-range", "3:"}, rules[2:]},
+		{[]string{"--rules-range", ":1"}, rules[:1]},
+		{[]string{"--head-rules", "3", "--rules-range", "2:"}, rules[1:3]},
+	}
+
+	for _, test := range tests {
+		selected, err := selectRules(rules, parseArgs(t, test.argv...))
+		if err != nil {
+			t.Fatalf("%v: %s", test.argv, err)
+		}
+
+		if !reflect.DeepEqual(score(selected), score(test.expected)) {
+			t.Errorf(
+				"%v: scores differ from manually truncated rules", test.argv,
+			)
+		}
+	}
+
+	for _, argv := range [][]string{
+		{"--head-rules", "-1"},
+		{"--head-rules", "many"},
+		{"--rules-range", "2"},
+		{"--rules-range", "0:2"},
+		{"--rules-range", "3:1"},
+	} {
+		_, err := selectRules(rules, parseArgs(t, argv...))
+		if err == nil {
+			t.Errorf("%v: expected error", argv)
+		}
+	}
+}
+
+func TestApplyRootBoost(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"README.md":        "",
+		"Makefile":         "",
+		"cmd/main.go":      "",
+		"cmd/tool/tool.go": "",
+		"internal/lib.go":  "",
+	})
+
+	rules := []Rule{newRule(t, Rule{Score: 1})}
+
+	files := walkTemp(t, &Config{Rules: rules})
+	files = applyRules(context.Background(), files, rules, nil)
+	files = applyRootBoost(files, 50)
+
+	expected := map[string]int{
+		"README.md":        51,
+		"Makefile":         51,
+		"cmd/main.go":      1,
+		"cmd/tool/tool.go": 1,
+		"internal/lib.go":  1,
+	}
+
+	for path, score := range expected {
+		if actual := findFile(files, path).Score; actual != score {
+			t.Errorf("%s: expected score %d, got %d", path, score, actual)
+		}
+	}
+}
+
+func TestDeadlineSlowLister(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+		"c.go": "",
+	})
+
+	// lister's stderr is redirected, otherwise sleep keeps captured stderr
+	// of prols open after prols exits
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "lister": ["sh", "-c", "exec 2>/dev/null; printf 'a.go\nb.go\n'; sleep 5; echo c.go"],
+    "rules": [{"suffix": ".go", "score": 10}]
+}`)
+
+	deadline := 300 * time.Millisecond
+	started := time.Now()
+
+	stdout, stderr, err := runProls(t, "-c", config, "--deadline", deadline.String())
+	if err != nil {
+		t.Fatalf("%s: %s", err, stderr)
+	}
+
+	if elapsed := time.Since(started); elapsed > deadline+500*time.Millisecond {
+		t.Errorf("prols should stop right after %s deadline, took %s", deadline, elapsed)
+	}
+
+	if stdout != "a.go\nb.go\n" {
+		t.Errorf("expected files listed before deadline, got %q", stdout)
+	}
+
+	if !strings.Contains(stderr, "files are not scored") {
+		t.Errorf("expected warning about unscored files, got %q", stderr)
+	}
+}
+
+func TestMarkFilesDeadline(t *testing.T) {
+	chdirTemp(t, map[string]string{"main.go": ""})
+
+	files := walkTemp(t, &Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rules := []Rule{newRule(t, Rule{SessionModified: boolPtr(true), Score: 1})}
+
+	err := markFiles(ctx, files, rules, "", time.Now())
+	if err == nil {
+		t.Errorf("markFiles should report done context")
+	}
+
+	if files[0].SessionModified != nil {
+		t.Errorf("properties should not be calculated after deadline")
+	}
+}
+
+func TestMinFiles(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+		"c.md": "",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "hide_negative": true,
+    "rules": [{"suffix": ".md", "score": -10}]
+}`)
+
+	stdout, _, err := runProls(t, "-c", config, "--min-files", "2")
+	if err != nil {
+		t.Fatalf("threshold is met, but prols failed: %s", err)
+	}
+
+	if stdout != "a.go\nb.go\n" {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	_, stderr, err := runProls(t, "-c", config, "--min-files", "3")
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() == 0 {
+		t.Fatalf("expected non-zero exit code, got %v", err)
+	}
+
+	if !strings.Contains(stderr, "only 2 files found, at least 3 expected") {
+		t.Errorf("unexpected error message: %q", stderr)
+	}
+}
+
+func TestReverseTies(t *testing.T) {
+	newFiles := func() []*File {
+		return []*File{
+			{Path: "a.go", Score: 10},
+			{Path: "b.go", Score: 5},
+			{Path: "c.go", Score: 10},
+			{Path: "d.go", Score: 5},
+			{Path: "e.go", Score: 1},
+		}
+	}
+
+	expected := []string{"e.go", "d.go", "b.go", "c.go", "a.go"}
+
+	for _, unstable := range []bool{false, true} {
+		files := applySortScore(newFiles(), unstable, true)
+
+		if paths := getPaths(files); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("unstable %v: expected %v, got %v", unstable, expected, paths)
+		}
+	}
+
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+		"c.md": "",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "reverse": true,
+    "presort": [{"field": "path"}],
+    "rules": [{"suffix": ".md", "score": 10}]
+}`)
+
+	stdout, _, err := runProls(t, "-c", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "c.md\nb.go\na.go\n" {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	stdout, _, err = runProls(t, "-c", config, "--reverse-ties")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "c.md\na.go\nb.go\n" {
+		t.Errorf("only ties should be reversed, got %q", stdout)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	// first word of wrapped description line must not look like an option
+	args := parseArgs(
+		t,
+		"--dry-run", "--benchmark-rules", "--record-walk", "walk.json",
+		"--export-features", "x.csv", "--explain-threshold", "5",
+	)
+
+	for name, value := range map[string]interface{}{
+		"--dry-run":           true,
+		"--benchmark-rules":   true,
+		"--record-walk":       "walk.json",
+		"--export-features":   "x.csv",
+		"--explain-threshold": "5",
+	} {
+		if args[name] != value {
+			t.Errorf("%s: expected %v, got %v", name, value, args[name])
+		}
+	}
+}
+
+func TestSkipHidden(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".git/HEAD":            "",
+		".venv/lib/site.py":    "",
+		".github/workflows/ci": "",
+		".env":                 "",
+		"main.go":              "",
+		"build/out.o":          "",
+		"pkg/.cache/artifact":  "",
+		"pkg/lib.go":           "",
+	})
+
+	lister := []string{"sh", "-c", "find . -type f | sed 's#^./##'"}
+
+	tests := []struct {
+		config   Config
+		expected []string
+	}{
+		{
+			Config{IgnoreDirs: []string{"build"}},
+			[]string{
+				".env", ".git/HEAD", ".github/workflows/ci",
+				".venv/lib/site.py", "main.go", "pkg/.cache/artifact",
+				"pkg/lib.go",
+			},
+		},
+		{
+			Config{SkipHidden: true, IgnoreDirs: []string{"build"}},
+			[]string{".env", "main.go", "pkg/lib.go"},
+		},
+		{
+			Config{SkipHidden: true, Lister: lister},
+			[]string{".env", "build/out.o", "main.go", "pkg/lib.go"},
+		},
+	}
+
+	for _, test := range tests {
+		files := walkTemp(t, &test.config)
+
+		paths := getPaths(files)
+		sort.Strings(paths)
+
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf(
+				"skip hidden %v, lister %v: expected %v, got %v",
+				test.config.SkipHidden, test.config.Lister != nil,
+				test.expected, paths,
+			)
+		}
+	}
+}
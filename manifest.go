@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var buildManifests = map[string]struct{}{
+	"Makefile":       {},
+	"makefile":       {},
+	"GNUmakefile":    {},
+	"BUILD":          {},
+	"BUILD.bazel":    {},
+	"CMakeLists.txt": {},
+}
+
+// markInBuildManifest marks files which are referenced by build manifests
+// located in the same directory or above, file is considered referenced if
+// manifest mentions its path relative to manifest's directory as a whole
+// word, so main.go is not matched by domain.go.
+func markInBuildManifest(files []*File) {
+	referenced := map[string]struct{}{}
+	for _, file := range files {
+		if _, ok := buildManifests[filepath.Base(file.Path)]; !ok {
+			continue
+		}
+
+		content, err := file.Content()
+		if err != nil {
+			continue
+		}
+
+		dir := filepath.Dir(filepath.Clean(file.Path))
+
+		for _, word := range strings.FieldsFunc(string(content), isNotPathRune) {
+			word = path.Clean(word)
+			if path.IsAbs(word) || word == ".." || strings.HasPrefix(word, "../") {
+				continue
+			}
+
+			referenced[filepath.Join(dir, filepath.FromSlash(word))] = struct{}{}
+		}
+	}
+
+	for _, file := range files {
+		_, ok := referenced[filepath.Clean(file.Path)]
+		file.InBuildManifest = &ok
+	}
+}
+
+// isNotPathRune reports whether given rune can't be a part of path mentioned
+// in build manifest.
+func isNotPathRune(char rune) bool {
+	switch {
+	case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		return false
+	case char >= '0' && char <= '9':
+		return false
+	}
+
+	return !strings.ContainsRune("_./-", char)
+}
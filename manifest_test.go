@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestInBuildManifest(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"Makefile": "build: domain.go lib/util.go\n" +
+			"\tgo build -o app ./cmd/run.go\n" +
+			"gen: gen/../gen/api.go\n",
+		"domain.go":       "",
+		"main.go":         "",
+		"in.go":           "",
+		"lib/util.go":     "",
+		"lib/util.go.bak": "",
+		"cmd/run.go":      "",
+		"cmd/rerun.go":    "",
+		"cmd/BUILD":       "srcs = [\"rerun.go\"]\n",
+		"orphan.go":       "",
+		"gen/api.go":      "",
+	})
+
+	rule := newRule(t, Rule{InBuildManifest: boolPtr(true)})
+
+	files := walkTemp(t, &Config{})
+	markInBuildManifest(files)
+
+	expected := map[string]bool{
+		"domain.go":       true,
+		"main.go":         false,
+		"in.go":           false,
+		"lib/util.go":     true,
+		"lib/util.go.bak": false,
+		"cmd/run.go":      true,
+		"cmd/rerun.go":    true,
+		"orphan.go":       false,
+		"gen/api.go":      true,
+	}
+
+	for path, referenced := range expected {
+		if rule.Pass(findFile(files, path)) != referenced {
+			t.Errorf("%s: expected referenced %v", path, referenced)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	MergeStrategySum = "sum"
+	MergeStrategyMax = "max"
+)
+
+// mergeFiles reads results previously printed with --json and unions them,
+// scores of files listed more than once are combined using given strategy.
+func mergeFiles(
+	paths []string,
+	strategy string,
+	ignoreCase bool,
+) ([]*File, error) {
+	if strategy != MergeStrategySum && strategy != MergeStrategyMax {
+		return nil, karma.
+			Describe("strategy", strategy).
+			Format(
+				nil,
+				"unexpected merge strategy, should be %s or %s",
+				MergeStrategySum, MergeStrategyMax,
+			)
+	}
+
+	files := []*File{}
+	index := map[string]*File{}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read %s", path,
+			)
+		}
+
+		var results []*File
+		err = json.Unmarshal(data, &results)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to decode %s", path,
+			)
+		}
+
+		for _, result := range results {
+			key := getPathKey(result.Path, ignoreCase)
+
+			file, ok := index[key]
+			if !ok {
+				index[key] = result
+				files = append(files, result)
+				continue
+			}
+
+			switch strategy {
+			case MergeStrategySum:
+				file.Score += result.Score
+			case MergeStrategyMax:
+				if result.Score > file.Score {
+					file.Score = result.Score
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
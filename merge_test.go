@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	outputs := map[string][]*File{
+		"a.json": {
+			{Path: "main.go", Score: 10},
+			{Path: "README.md", Score: 3},
+		},
+		"b.json": {
+			{Path: "main.go", Score: 4},
+			{Path: "util.go", Score: 7},
+		},
+	}
+
+	paths := []string{}
+	for _, name := range []string{"a.json", "b.json"} {
+		var buffer bytes.Buffer
+
+		err := printFiles(&buffer, outputs[name], true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(dir, name)
+
+		err = os.WriteFile(path, buffer.Bytes(), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	for strategy, expected := range map[string]map[string]int{
+		MergeStrategySum: {"main.go": 14, "README.md": 3, "util.go": 7},
+		MergeStrategyMax: {"main.go": 10, "README.md": 3, "util.go": 7},
+	} {
+		files, err := mergeFiles(paths, strategy, false)
+		if err != nil {
+			t.Fatalf("%s: %s", strategy, err)
+		}
+
+		if len(files) != len(expected) {
+			t.Errorf("%s: got %v, want %d files", strategy, getPaths(files), len(expected))
+		}
+
+		for path, score := range expected {
+			file := findFile(files, path)
+			if file == nil {
+				t.Errorf("%s: %s is missing", strategy, path)
+				continue
+			}
+
+			if file.Score != score {
+				t.Errorf("%s: %s score = %d, want %d", strategy, path, file.Score, score)
+			}
+		}
+	}
+
+	_, err := mergeFiles(paths, "avg", false)
+	if err == nil {
+		t.Errorf("unknown strategy should be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var modeShifts = map[byte]uint{
+	'u': 6,
+	'g': 3,
+	'o': 0,
+}
+
+var modeBits = map[byte]os.FileMode{
+	'r': 4,
+	'w': 2,
+	'x': 1,
+}
+
+// parseMode parses permissions given as octal number (0755), symbolic
+// string (rwxr-xr-x) or comma-separated list of chmod-like clauses
+// (u+x,g-w) into mask of permission bits to check and their expected values.
+func parseMode(spec string) (os.FileMode, os.FileMode, error) {
+	if spec == "" {
+		return 0, 0, fmt.Errorf("empty mode")
+	}
+
+	if spec[0] >= '0' && spec[0] <= '7' {
+		value, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil || value > 0777 {
+			return 0, 0, fmt.Errorf("invalid octal mode: %q", spec)
+		}
+
+		return 0777, os.FileMode(value), nil
+	}
+
+	if len(spec) == 9 && !strings.ContainsAny(spec, "+=,") {
+		var value os.FileMode
+		for i := 0; i < 9; i++ {
+			expected := "rwx"[i%3]
+			switch spec[i] {
+			case expected:
+				value |= 1 << uint(8-i)
+			case '-':
+			default:
+				return 0, 0, fmt.Errorf(
+					"invalid symbolic mode: %q, expected %c or - at position %d",
+					spec, expected, i+1,
+				)
+			}
+		}
+
+		return 0777, value, nil
+	}
+
+	var mask, value os.FileMode
+	for _, clause := range strings.Split(spec, ",") {
+		index := strings.IndexAny(clause, "+-=")
+		if index < 0 {
+			return 0, 0, fmt.Errorf(
+				"invalid mode clause: %q, expected one of +, - or =", clause,
+			)
+		}
+
+		who := clause[:index]
+		if who == "" || who == "a" {
+			who = "ugo"
+		}
+
+		var bits os.FileMode
+		for _, char := range []byte(clause[index+1:]) {
+			bit, ok := modeBits[char]
+			if !ok {
+				return 0, 0, fmt.Errorf(
+					"invalid mode clause: %q, unexpected permission %c",
+					clause, char,
+				)
+			}
+
+			bits |= bit
+		}
+
+		if bits == 0 && clause[index] != '=' {
+			return 0, 0, fmt.Errorf(
+				"invalid mode clause: %q, no permissions given", clause,
+			)
+		}
+
+		for _, char := range []byte(who) {
+			shift, ok := modeShifts[char]
+			if !ok {
+				return 0, 0, fmt.Errorf(
+					"invalid mode clause: %q, unexpected class %c",
+					clause, char,
+				)
+			}
+
+			switch clause[index] {
+			case '+':
+				mask |= bits << shift
+				value |= bits << shift
+			case '-':
+				mask |= bits << shift
+				value &^= bits << shift
+			case '=':
+				mask |= 7 << shift
+				value = value&^(7<<shift) | bits<<shift
+			}
+		}
+	}
+
+	return mask, value, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		spec    string
+		mode    os.FileMode
+		matches bool
+	}{
+		{"0755", 0755, true},
+		{"0755", 0775, false},
+		{"rwxr-xr-x", 0755, true},
+		{"rwxr-xr-x", 0644, false},
+		{"rw-r--r--", 0644, true},
+		{"+x", 0755, true},
+		{"+x", 0744, false},
+		{"u+x", 0700, true},
+		{"u+x", 0644, false},
+		{"u+x,g-w", 0754, true},
+		{"u+x,g-w", 0774, false},
+		{"o=", 0750, true},
+		{"o=", 0751, false},
+		{"go=r", 0744, true},
+	}
+
+	for _, test := range tests {
+		mask, value, err := parseMode(test.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", test.spec, err)
+		}
+
+		if matches := test.mode&mask == value; matches != test.matches {
+			t.Errorf(
+				"%s: mode %o: expected match %v", test.spec, test.mode, test.matches,
+			)
+		}
+	}
+}
+
+func TestParseModeInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"", "0999", "01777", "rwxr-xr-y", "x", "u+", "z+x", "u+q",
+	} {
+		_, _, err := parseMode(spec)
+		if err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestRuleMode(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"run.sh":    "#!/bin/sh\n",
+		"README.md": "",
+	})
+
+	err := os.Chmod("run.sh", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chmod("README.md", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := newRule(t, Rule{Mode: "u+x"})
+
+	files := walkTemp(t, &Config{})
+
+	if !rule.Pass(findFile(files, "run.sh")) {
+		t.Errorf("run.sh: executable file should match u+x")
+	}
+
+	if rule.Pass(findFile(files, "README.md")) {
+		t.Errorf("README.md: non-executable file should not match u+x")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/reconquest/karma-go"
+	"golang.org/x/text/unicode/norm"
+)
+
+func parseNormalizationForm(name string) (norm.Form, error) {
+	switch strings.ToUpper(name) {
+	case "NFC":
+		return norm.NFC, nil
+	case "NFD":
+		return norm.NFD, nil
+	default:
+		return 0, karma.Format(
+			nil,
+			"unexpected normalization form: %s, should be NFC or NFD", name,
+		)
+	}
+}
+
+// normalizeMatchPaths makes rules see paths of files in given form while
+// files are still accessed and printed using their original paths.
+func normalizeMatchPaths(files []*File, form norm.Form) {
+	for _, file := range files {
+		file.matchPath = form.String(file.Path)
+	}
+}
+
+// normalizeRule converts path patterns of rule into given form so they can
+// be compared with normalized paths.
+func normalizeRule(rule *Rule, form norm.Form) {
+	rule.Prefix = form.String(rule.Prefix)
+	rule.Suffix = form.String(rule.Suffix)
+	rule.Except = form.String(rule.Except)
+
+	if rule.AncestorDir != nil {
+		rule.AncestorDir.Pattern = form.String(rule.AncestorDir.Pattern)
+	}
+}
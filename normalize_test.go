@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestNormalizePaths(t *testing.T) {
+	nfc := norm.NFC.String("café")
+	nfd := norm.NFD.String("café")
+
+	if nfc == nfd {
+		t.Fatal("normalization forms of test name should differ")
+	}
+
+	chdirTemp(t, map[string]string{
+		nfc + "-menu.txt": "",
+		nfd + "-bill.txt": "",
+		"tea.txt":         "",
+	})
+
+	for _, form := range []string{"NFC", "NFD"} {
+		for _, prefix := range []string{nfc, nfd} {
+			path := filepath.Join(t.TempDir(), "prols.json")
+			writeFile(
+				t, path,
+				`{"ignore_dirs": [".git"], "normalize_paths": "`+form+`", "rules": [`+
+					`{"prefix": "`+prefix+`", "score": 10}]}`,
+			)
+
+			config, err := LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := walkTemp(t, config)
+			normalizeMatchPaths(files, config.normalizeForm)
+			files = applyRules(context.Background(), files, config.Rules, nil)
+
+			for _, file := range files {
+				expected := 10
+				if file.Path == "tea.txt" {
+					expected = 0
+				}
+
+				if file.Score != expected {
+					t.Errorf(
+						"%s: prefix %q: %q: expected score %d, got %d",
+						form, prefix, file.Path, expected, file.Score,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizeAncestorDir(t *testing.T) {
+	nfc := norm.NFC.String("café")
+	nfd := norm.NFD.String("café")
+
+	chdirTemp(t, map[string]string{
+		nfc + "/menu.txt": "",
+		"tea/menu.txt":    "",
+	})
+
+	for _, form := range []string{"NFC", "NFD"} {
+		for _, pattern := range []string{nfc, nfd} {
+			path := filepath.Join(t.TempDir(), "prols.json")
+			writeFile(
+				t, path,
+				`{"ignore_dirs": [".git"], "normalize_paths": "`+form+`", "rules": [`+
+					`{"ancestor_dir": {"pattern": "`+pattern+`"}, "score": 10}]}`,
+			)
+
+			config, err := LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := walkTemp(t, config)
+			normalizeMatchPaths(files, config.normalizeForm)
+			files = applyRules(context.Background(), files, config.Rules, nil)
+
+			for _, file := range files {
+				expected := 10
+				if file.Path == "tea/menu.txt" {
+					expected = 0
+				}
+
+				if file.Score != expected {
+					t.Errorf(
+						"%s: pattern %q: %q: expected score %d, got %d",
+						form, pattern, file.Path, expected, file.Score,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizePathsOutput(t *testing.T) {
+	nfd := norm.NFD.String("café.txt")
+
+	chdirTemp(t, map[string]string{nfd: ""})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{"ignore_dirs": [".git"], "rules": []}`)
+
+	stdout, _, err := runProls(t, "-c", config, "--normalize-paths", "NFC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(stdout) != norm.NFC.String("café.txt") {
+		t.Errorf("expected path printed in NFC, got %q", stdout)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+var defaultScoreBuckets = []int{100, 50, 0}
+
+func printFiles(output io.Writer, files []*File, jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "    ")
+
+		return encoder.Encode(files)
+	}
+
+	for _, file := range files {
+		_, err := fmt.Fprintln(output, file.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printJSONLines prints every file as separate JSON object on its own line,
+// so output can be consumed without reading it whole.
+func printJSONLines(output io.Writer, files []*File) error {
+	encoder := json.NewEncoder(output)
+
+	for _, file := range files {
+		err := encoder.Encode(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getBucketHeader returns header of bucket which given score belongs to,
+// boundaries should be sorted in descending order.
+func getBucketHeader(score int, boundaries []int) string {
+	for i, boundary := range boundaries {
+		if score < boundary {
+			continue
+		}
+
+		if i == 0 {
+			return fmt.Sprintf("=== score >= %d ===", boundary)
+		}
+
+		return fmt.Sprintf("=== %d..%d ===", boundary, boundaries[i-1]-1)
+	}
+
+	return fmt.Sprintf("=== score < %d ===", boundaries[len(boundaries)-1])
+}
+
+// printBucketed prints files grouped under headers of score buckets, files
+// are kept in the given order and a header is printed every time bucket
+// changes.
+func printBucketed(output io.Writer, files []*File, boundaries []int) error {
+	if len(boundaries) == 0 {
+		boundaries = defaultScoreBuckets
+	}
+
+	sorted := append([]int{}, boundaries...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	previous := ""
+	for _, file := range files {
+		header := getBucketHeader(file.Score, sorted)
+		if header != previous {
+			_, err := fmt.Fprintln(output, header)
+			if err != nil {
+				return err
+			}
+
+			previous = header
+		}
+
+		_, err := fmt.Fprintln(output, file.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Printf is a compiled --printf format, every segment is either a literal
+// text or a single verb.
+type Printf struct {
+	segments []printfSegment
+}
+
+type printfSegment struct {
+	text string
+	verb byte
+}
+
+// parsePrintf compiles find-like format where %p is path, %s is score, %d
+// is depth, %z is size and %% is a percent sign.
+func parsePrintf(format string) (*Printf, error) {
+	printf := &Printf{}
+
+	text := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+
+		if i+1 == len(format) {
+			return nil, karma.Format(
+				nil,
+				"format ends with unfinished verb: %q", format,
+			)
+		}
+
+		i++
+
+		switch format[i] {
+		case '%':
+			text.WriteByte('%')
+		case 'p', 's', 'd', 'z':
+			if text.Len() > 0 {
+				printf.segments = append(
+					printf.segments,
+					printfSegment{text: text.String()},
+				)
+				text.Reset()
+			}
+
+			printf.segments = append(
+				printf.segments,
+				printfSegment{verb: format[i]},
+			)
+		default:
+			return nil, karma.Format(
+				nil,
+				"unknown verb %%%c in format %q", format[i], format,
+			)
+		}
+	}
+
+	if text.Len() > 0 {
+		printf.segments = append(printf.segments, printfSegment{text: text.String()})
+	}
+
+	return printf, nil
+}
+
+func (printf *Printf) Format(file *File) string {
+	result := strings.Builder{}
+	for _, segment := range printf.segments {
+		switch segment.verb {
+		case 'p':
+			result.WriteString(file.Path)
+		case 's':
+			result.WriteString(strconv.Itoa(file.Score))
+		case 'd':
+			result.WriteString(strconv.Itoa(file.Depth()))
+		case 'z':
+			result.WriteString(strconv.FormatInt(file.Size, 10))
+		default:
+			result.WriteString(segment.text)
+		}
+	}
+
+	return result.String()
+}
+
+func printFormatted(output io.Writer, files []*File, printf *Printf) error {
+	for _, file := range files {
+		_, err := fmt.Fprintln(output, printf.Format(file))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintBucketed(t *testing.T) {
+	files := []*File{
+		{Path: "top.go", Score: 150},
+		{Path: "edge.go", Score: 100},
+		{Path: "middle.go", Score: 99},
+		{Path: "half.go", Score: 50},
+		{Path: "low.go", Score: 10},
+		{Path: "zero.go", Score: 0},
+		{Path: "negative.go", Score: -5},
+	}
+
+	tests := []struct {
+		boundaries []int
+		expected   string
+	}{
+		{
+			nil,
+			"=== score >= 100 ===\ntop.go\nedge.go\n" +
+				"=== 50..99 ===\nmiddle.go\nhalf.go\n" +
+				"=== 0..49 ===\nlow.go\nzero.go\n" +
+				"=== score < 0 ===\nnegative.go\n",
+		},
+		{
+			[]int{10, 120},
+			"=== score >= 120 ===\ntop.go\n" +
+				"=== 10..119 ===\nedge.go\nmiddle.go\nhalf.go\nlow.go\n" +
+				"=== score < 10 ===\nzero.go\nnegative.go\n",
+		},
+	}
+
+	for _, test := range tests {
+		output := &bytes.Buffer{}
+
+		err := printBucketed(output, files, test.boundaries)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output.String() != test.expected {
+			t.Errorf(
+				"%v: unexpected output:\n%s\nexpected:\n%s",
+				test.boundaries, output.String(), test.expected,
+			)
+		}
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	files := []*File{
+		{Path: "main.go", Score: 10, Size: 120},
+		{Path: "cmd/tool/run.go", Score: -3, Size: 0},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"%s %p", "10 main.go\n-3 cmd/tool/run.go\n"},
+		{"%p:%d:%z", "main.go:1:120\ncmd/tool/run.go:3:0\n"},
+		{"100%% %p", "100% main.go\n100% cmd/tool/run.go\n"},
+		{"static", "static\nstatic\n"},
+	}
+
+	for _, test := range tests {
+		printf, err := parsePrintf(test.format)
+		if err != nil {
+			t.Fatalf("%q: %s", test.format, err)
+		}
+
+		output := &bytes.Buffer{}
+
+		err = printFormatted(output, files, printf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output.String() != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.format, test.expected, output)
+		}
+	}
+
+	for _, format := range []string{"%q", "%p %", "%P"} {
+		_, err := parsePrintf(format)
+		if err == nil {
+			t.Errorf("%q: expected error", format)
+		}
+	}
+}
+
+func TestPrintJSONLines(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	files := []*File{
+		{Path: "main.go", Score: 10, Size: 120, ModTime: modTime},
+		{Path: "docs/a b.md", Score: -3, Binary: true, ModTime: modTime},
+	}
+
+	output := &bytes.Buffer{}
+
+	err := printJSONLines(output, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != len(files) {
+		t.Fatalf("expected %d lines, got %q", len(files), output)
+	}
+
+	for i, line := range lines {
+		var file File
+
+		err := json.Unmarshal([]byte(line), &file)
+		if err != nil {
+			t.Fatalf("line %d: %s", i+1, err)
+		}
+
+		if !reflect.DeepEqual(&file, files[i]) {
+			t.Errorf("line %d: expected %+v, got %+v", i+1, files[i], file)
+		}
+	}
+}
+
+func TestPrintJSONLinesLazyProperties(t *testing.T) {
+	commitTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ignored, depth, ratio := false, 0, 0.0
+
+	files := []*File{
+		{Path: "main.go", ModTime: commitTime},
+		{
+			Path:              "lib.go",
+			ModTime:           commitTime,
+			GitIgnored:        &ignored,
+			RepoDepth:         &depth,
+			CommitTime:        &commitTime,
+			SessionModified:   &ignored,
+			InBuildManifest:   &ignored,
+			NonPrintableRatio: &ratio,
+		},
+	}
+
+	output := &bytes.Buffer{}
+
+	err := printJSONLines(output, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != len(files) {
+		t.Fatalf("expected %d lines, got %q", len(files), output)
+	}
+
+	properties := []string{
+		"gitignored", "repo_depth", "commit_time",
+		"session_modified", "in_build_manifest", "non_printable_ratio",
+	}
+
+	for _, property := range properties {
+		if strings.Contains(lines[0], `"`+property+`"`) {
+			t.Errorf("%s should be omitted when not calculated: %s", property, lines[0])
+		}
+
+		if !strings.Contains(lines[1], `"`+property+`"`) {
+			t.Errorf("%s should be present when calculated: %s", property, lines[1])
+		}
+	}
+
+	var file File
+
+	err = json.Unmarshal([]byte(lines[1]), &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(&file, files[1]) {
+		t.Errorf("expected %+v, got %+v", files[1], file)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const progressInterval = 200 * time.Millisecond
+
+// Progress periodically reports number of discovered and scored files to
+// terminal, all methods are no-op on nil progress.
+type Progress struct {
+	output     io.Writer
+	discovered int64
+	scored     int64
+	stop       chan struct{}
+	wait       sync.WaitGroup
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// startProgress starts reporting progress to given output, nil is returned
+// if output is not a terminal.
+func startProgress(output *os.File) *Progress {
+	if !isTerminal(output) {
+		return nil
+	}
+
+	return runProgress(output)
+}
+
+// runProgress starts reporting progress to given output unconditionally.
+func runProgress(output io.Writer) *Progress {
+	progress := &Progress{
+		output: output,
+		stop:   make(chan struct{}),
+	}
+
+	progress.wait.Add(1)
+	go progress.loop()
+
+	return progress
+}
+
+func (progress *Progress) loop() {
+	defer progress.wait.Done()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprintf(
+				progress.output,
+				"\rdiscovered: %d, scored: %d",
+				atomic.LoadInt64(&progress.discovered),
+				atomic.LoadInt64(&progress.scored),
+			)
+
+		case <-progress.stop:
+			fmt.Fprint(progress.output, "\r\033[K")
+			return
+		}
+	}
+}
+
+func (progress *Progress) Discover() {
+	if progress != nil {
+		atomic.AddInt64(&progress.discovered, 1)
+	}
+}
+
+func (progress *Progress) Score() {
+	if progress != nil {
+		atomic.AddInt64(&progress.scored, 1)
+	}
+}
+
+// Stop stops reporting and clears progress line.
+func (progress *Progress) Stop() {
+	if progress != nil {
+		close(progress.stop)
+		progress.wait.Wait()
+	}
+}
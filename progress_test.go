@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	var output bytes.Buffer
+
+	progress := runProgress(&output)
+	for i := 0; i < 3; i++ {
+		progress.Discover()
+	}
+
+	progress.Score()
+
+	time.Sleep(progressInterval * 2)
+	progress.Stop()
+
+	if !strings.Contains(output.String(), "discovered: 3, scored: 1") {
+		t.Errorf("unexpected progress output: %q", output.String())
+	}
+
+	if !strings.HasSuffix(output.String(), "\r\033[K") {
+		t.Errorf("progress line should be cleared when done: %q", output.String())
+	}
+}
+
+func TestProgressNotTerminal(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	progress := startProgress(file)
+	if progress != nil {
+		t.Fatalf("progress should not be reported if output is not a terminal")
+	}
+
+	// nil progress is no-op
+	progress.Discover()
+	progress.Score()
+	progress.Stop()
+
+	chdirTemp(t, map[string]string{
+		"a.go": "",
+		"b.go": "",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{"ignore_dirs": [".git"], "rules": []}`)
+
+	stdout, stderr, err := runProls(t, "-c", config, "--progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout != "a.go\nb.go\n" {
+		t.Errorf("stdout should contain only files, got %q", stdout)
+	}
+
+	if strings.Contains(stderr, "discovered") {
+		t.Errorf("progress should not be printed, got %q", stderr)
+	}
+}
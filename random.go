@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// selectWeighted picks single file at random with probability proportional
+// to its score, files with non-positive score are never picked; nil is
+// returned if there are no files with positive score.
+func selectWeighted(files []*File, random *rand.Rand) *File {
+	total := 0
+	for _, file := range files {
+		if file.Score > 0 {
+			total += file.Score
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	target := random.Intn(total)
+	for _, file := range files {
+		if file.Score <= 0 {
+			continue
+		}
+
+		if target < file.Score {
+			return file
+		}
+
+		target -= file.Score
+	}
+
+	return nil
+}
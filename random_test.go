@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSelectWeighted(t *testing.T) {
+	files := []*File{
+		{Path: "high", Score: 60},
+		{Path: "medium", Score: 30},
+		{Path: "low", Score: 10},
+		{Path: "zero", Score: 0},
+		{Path: "negative", Score: -50},
+	}
+
+	const runs = 10000
+
+	counts := map[string]int{}
+	for seed := int64(0); seed < runs; seed++ {
+		file := selectWeighted(files, rand.New(rand.NewSource(seed)))
+		if file == nil {
+			t.Fatalf("seed %d: no file selected", seed)
+		}
+
+		counts[file.Path]++
+	}
+
+	for _, path := range []string{"zero", "negative"} {
+		if counts[path] != 0 {
+			t.Errorf("%s: non-positive score file selected %d times", path, counts[path])
+		}
+	}
+
+	for _, file := range files[:3] {
+		expected := float64(file.Score) / 100
+		actual := float64(counts[file.Path]) / runs
+
+		if math.Abs(expected-actual) > 0.03 {
+			t.Errorf("%s: expected ratio %.2f, got %.3f", file.Path, expected, actual)
+		}
+	}
+}
+
+func TestSelectWeightedNoPositive(t *testing.T) {
+	files := []*File{{Path: "a", Score: 0}, {Path: "b", Score: -1}}
+
+	if file := selectWeighted(files, rand.New(rand.NewSource(1))); file != nil {
+		t.Errorf("expected no file, got %s", file.Path)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/reconquest/karma-go"
+)
+
+// recordWalk saves files found by walk along with their metadata, so the
+// same list can be scored later without walking directory.
+func recordWalk(path string, files []*File) error {
+	data, err := json.MarshalIndent(files, "", "    ")
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to encode files",
+		)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to write %s", path,
+		)
+	}
+
+	return nil
+}
+
+// replayWalk loads files saved by recordWalk, scores recorded at walk time
+// are dropped.
+func replayWalk(path string) ([]*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read %s", path,
+		)
+	}
+
+	var files []*File
+	err = json.Unmarshal(data, &files)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to decode %s", path,
+		)
+	}
+
+	for _, file := range files {
+		file.Score = 0
+	}
+
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplayWalk(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":      "package main\n",
+		"big.go":       strings.Repeat("x", 2000),
+		"docs/doc.md":  "# docs\n",
+		"logo.png":     "\x89PNG\r\n\x1a\n\x00\x00\x00\x00",
+		"cmd/tool.go":  "package main\n",
+		"vendor/x.go":  "package x\n",
+		"vendor/y.txt": "",
+	})
+
+	dir := t.TempDir()
+
+	config := filepath.Join(dir, "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "rules": [
+        {"suffix": ".go", "score": 10},
+        {"binary": true, "score": -10},
+        {"size": ">1000", "score": 5},
+        {"depth": ">1", "score": -1}
+    ]
+}`)
+
+	record := filepath.Join(dir, "walk.json")
+
+	recorded, stderr, err := runProls(
+		t, "-c", config, "--json", "--record-walk", record,
+	)
+	if err != nil {
+		t.Fatalf("%s: %s", err, stderr)
+	}
+
+	// replay should not need the tree at all
+	for _, path := range []string{"main.go", "big.go", "logo.png", "docs", "cmd", "vendor"} {
+		err := os.RemoveAll(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replayed, stderr, err := runProls(
+		t, "-c", config, "--json", "--replay-walk", record,
+	)
+	if err != nil {
+		t.Fatalf("%s: %s", err, stderr)
+	}
+
+	if replayed != recorded {
+		t.Errorf(
+			"replayed output differs from recorded:\n%s\nrecorded:\n%s",
+			replayed, recorded,
+		)
+	}
+}
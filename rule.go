@@ -1,22 +1,126 @@
 package main
 
 import (
+	"encoding/binary"
 	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/go-yaml/yaml"
 	"github.com/reconquest/karma-go"
 )
 
+const (
+	PositionFirst = "first"
+	PositionLast  = "last"
+)
+
+var (
+	defaultTestSuffixes = []string{
+		"_test.go",
+		".test.js", ".spec.js",
+		".test.ts", ".spec.ts",
+		".test.jsx", ".spec.jsx",
+		".test.tsx", ".spec.tsx",
+		"_test.py", "_spec.rb",
+	}
+
+	defaultTestDirs = []string{"test", "tests", "__tests__", "spec"}
+
+	defaultGeneratedPatterns = []string{
+		`(?m)^// Code generated .* DO NOT EDIT\.$`,
+		`@generated`,
+	}
+)
+
+const (
+	defaultLicensePattern = `SPDX-License-Identifier:`
+	defaultLicenseLines   = 10
+	defaultGeneratedLines = 10
+)
+
+// AncestorDir matches name of file's ancestor directory at given level, where
+// 1 is parent directory, 2 is grandparent and so on.
+type AncestorDir struct {
+	Level   int    `yaml:"level" json:"level"`
+	Pattern string `yaml:"pattern" json:"pattern" required:"true"`
+}
+
 type Rule struct {
-	Suffix          string `yaml:"suffix,omitempty"`
-	Prefix          string `yaml:"prefix,omitempty"`
-	Depth           string `yaml:"depth,omitempty"`
-	depthValue      int
-	depthComparison byte
-	Binary          *bool `yaml:"binary,omitempty"`
-	Score           int   `yaml:"score" required:"true"`
+	Suffix             string   `yaml:"suffix,omitempty" json:"suffix,omitempty"`
+	Prefix             string   `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Except             string   `yaml:"except,omitempty" json:"except,omitempty"`
+	HasNumericSegment  *bool    `yaml:"has_numeric_segment,omitempty" json:"has_numeric_segment,omitempty"`
+	PositionInDir      string   `yaml:"position_in_dir,omitempty" json:"position_in_dir,omitempty"`
+	IsTest             *bool    `yaml:"is_test,omitempty" json:"is_test,omitempty"`
+	TestSuffixes       []string `yaml:"test_suffixes,omitempty" json:"test_suffixes,omitempty"`
+	TestDirs           []string `yaml:"test_dirs,omitempty" json:"test_dirs,omitempty"`
+	NumericMin         *int     `yaml:"numeric_min,omitempty" json:"numeric_min,omitempty"`
+	NumericMax         *int     `yaml:"numeric_max,omitempty" json:"numeric_max,omitempty"`
+	UppercaseRatio     string   `yaml:"uppercase_ratio,omitempty" json:"uppercase_ratio,omitempty"`
+	uppercaseRatio     Comparison
+	except             []IgnorePattern
+	AncestorDir        *AncestorDir `yaml:"ancestor_dir,omitempty" json:"ancestor_dir,omitempty"`
+	Depth              string       `yaml:"depth,omitempty" json:"depth,omitempty"`
+	depth              Comparison
+	RepoDepth          string `yaml:"repo_depth,omitempty" json:"repo_depth,omitempty"`
+	repoDepth          Comparison
+	AccumulatedScore   string `yaml:"accumulated_score,omitempty" json:"accumulated_score,omitempty"`
+	accumulatedScore   Comparison
+	SizePercentile     string `yaml:"size_percentile,omitempty" json:"size_percentile,omitempty"`
+	sizePercentile     Comparison
+	DirSizeRatio       string `yaml:"dir_size_ratio,omitempty" json:"dir_size_ratio,omitempty"`
+	dirSizeRatio       Comparison
+	Binary             *bool  `yaml:"binary,omitempty" json:"binary,omitempty"`
+	GitIgnored         *bool  `yaml:"gitignored,omitempty" json:"gitignored,omitempty"`
+	Sparse             *bool  `yaml:"sparse,omitempty" json:"sparse,omitempty"`
+	MinLinks           int    `yaml:"min_links,omitempty" json:"min_links,omitempty"`
+	MaxLinks           int    `yaml:"max_links,omitempty" json:"max_links,omitempty"`
+	Mode               string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	modeMask           os.FileMode
+	modeValue          os.FileMode
+	SizeChanged        string `yaml:"size_changed,omitempty" json:"size_changed,omitempty"`
+	SessionModified    *bool  `yaml:"session_modified,omitempty" json:"session_modified,omitempty"`
+	Uncommitted        *bool  `yaml:"uncommitted,omitempty" json:"uncommitted,omitempty"`
+	InBuildManifest    *bool  `yaml:"in_build_manifest,omitempty" json:"in_build_manifest,omitempty"`
+	Errored            *bool  `yaml:"errored,omitempty" json:"errored,omitempty"`
+	GoParses           *bool  `yaml:"go_parses,omitempty" json:"go_parses,omitempty"`
+	Owner              string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	LinePattern        string `yaml:"line_pattern,omitempty" json:"line_pattern,omitempty"`
+	linePattern        *regexp.Regexp
+	LineCount          string `yaml:"line_count,omitempty" json:"line_count,omitempty"`
+	lineCount          Comparison
+	LineRatio          string `yaml:"line_ratio,omitempty" json:"line_ratio,omitempty"`
+	lineRatio          Comparison
+	LicenseHeader      *bool  `yaml:"license_header,omitempty" json:"license_header,omitempty"`
+	LicensePattern     string `yaml:"license_pattern,omitempty" json:"license_pattern,omitempty"`
+	licensePattern     *regexp.Regexp
+	LicenseLines       int    `yaml:"license_lines,omitempty" json:"license_lines,omitempty"`
+	LineEnding         string `yaml:"line_ending,omitempty" json:"line_ending,omitempty"`
+	NonPrintableRatio  string `yaml:"non_printable_ratio,omitempty" json:"non_printable_ratio,omitempty"`
+	nonPrintableRatio  Comparison
+	Keywords           string   `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	Generated          *bool    `yaml:"generated,omitempty" json:"generated,omitempty"`
+	HasConflictMarkers *bool    `yaml:"has_conflict_markers,omitempty" json:"has_conflict_markers,omitempty"`
+	IsContentDuplicate *bool    `yaml:"is_content_duplicate,omitempty" json:"is_content_duplicate,omitempty"`
+	GeneratedPatterns  []string `yaml:"generated_patterns,omitempty" json:"generated_patterns,omitempty"`
+	generatedPatterns  []*regexp.Regexp
+	keywords           [][]byte
+	ContainsAll        []string `yaml:"contains_all,omitempty" json:"contains_all,omitempty"`
+	Score              int      `yaml:"score" json:"score" required:"true"`
+	ScoreMin           *int     `yaml:"score_min,omitempty" json:"score_min,omitempty"`
+	ScoreMax           *int     `yaml:"score_max,omitempty" json:"score_max,omitempty"`
+	Buckets            []int    `yaml:"buckets,omitempty" json:"buckets,omitempty"`
+	Seed               uint64   `yaml:"seed,omitempty" json:"seed,omitempty"`
+	NearHere           bool     `yaml:"near_here,omitempty" json:"near_here,omitempty"`
+	DirRecency         string   `yaml:"dir_recency,omitempty" json:"dir_recency,omitempty"`
+	dirRecency         time.Duration
 }
 
 func (rule Rule) String() string {
@@ -35,35 +139,248 @@ func (rule *Rule) init() error {
 	var err error
 
 	if rule.Depth != "" {
-		var value int
+		rule.depth, err = parseComparison(rule.Depth)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid depth value",
+			)
+		}
+	}
 
-		sign := rule.Depth[0]
-		if sign == '<' || sign == '>' {
-			if len(rule.Depth) == 1 {
-				return errors.New("invalid depth value")
-			}
+	if rule.AncestorDir != nil {
+		if rule.AncestorDir.Level == 0 {
+			rule.AncestorDir.Level = 1
+		}
 
-			value, err = strconv.Atoi(rule.Depth[1:])
-			if err != nil {
-				return karma.Format(
-					err,
-					"invalid depth value",
-				)
-			}
-		} else {
-			value, err = strconv.Atoi(rule.Depth)
+		if rule.AncestorDir.Level < 0 {
+			return karma.Format(
+				nil,
+				"invalid ancestor dir level, should be positive: %d",
+				rule.AncestorDir.Level,
+			)
+		}
+
+		_, err = filepath.Match(rule.AncestorDir.Pattern, "")
+		if err != nil || rule.AncestorDir.Pattern == "" {
+			return karma.Format(
+				err,
+				"invalid ancestor dir pattern",
+			)
+		}
+	}
+
+	if rule.UppercaseRatio != "" {
+		rule.uppercaseRatio, err = parseComparison(rule.UppercaseRatio)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid uppercase ratio value",
+			)
+		}
+	}
+
+	if rule.AccumulatedScore != "" {
+		rule.accumulatedScore, err = parseComparison(rule.AccumulatedScore)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid accumulated score value",
+			)
+		}
+	}
+
+	if rule.RepoDepth != "" {
+		rule.repoDepth, err = parseComparison(rule.RepoDepth)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid repo depth value",
+			)
+		}
+	}
+
+	if rule.SizePercentile != "" {
+		rule.sizePercentile, err = parseComparison(rule.SizePercentile)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid size percentile value",
+			)
+		}
+	}
+
+	if rule.DirSizeRatio != "" {
+		rule.dirSizeRatio, err = parseComparison(rule.DirSizeRatio)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid dir size ratio value",
+			)
+		}
+	}
+
+	if rule.Except != "" {
+		rule.except, err = parseIgnorePattern(rule.Except, "", true)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid except pattern",
+			)
+		}
+	}
+
+	if rule.LinePattern != "" {
+		rule.linePattern, err = regexp.Compile(rule.LinePattern)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid line pattern",
+			)
+		}
+
+		if rule.LineCount == "" && rule.LineRatio == "" {
+			rule.LineCount = ">0"
+		}
+	}
+
+	if rule.LineCount != "" {
+		rule.lineCount, err = parseComparison(rule.LineCount)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid line count value",
+			)
+		}
+	}
+
+	if rule.LineRatio != "" {
+		rule.lineRatio, err = parseComparison(rule.LineRatio)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid line ratio value",
+			)
+		}
+	}
+
+	if rule.NonPrintableRatio != "" {
+		rule.nonPrintableRatio, err = parseComparison(rule.NonPrintableRatio)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid non-printable ratio value",
+			)
+		}
+	}
+
+	if rule.Mode != "" {
+		rule.modeMask, rule.modeValue, err = parseMode(rule.Mode)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid mode value",
+			)
+		}
+	}
+
+	if rule.IsTest != nil {
+		if len(rule.TestSuffixes) == 0 {
+			rule.TestSuffixes = defaultTestSuffixes
+		}
+
+		if len(rule.TestDirs) == 0 {
+			rule.TestDirs = defaultTestDirs
+		}
+	}
+
+	switch rule.LineEnding {
+	case "", LineEndingLF, LineEndingCRLF, LineEndingCR, LineEndingMixed:
+	default:
+		return karma.Format(
+			nil,
+			"invalid line ending value, should be %s, %s, %s or %s",
+			LineEndingLF, LineEndingCRLF, LineEndingCR, LineEndingMixed,
+		)
+	}
+
+	switch rule.PositionInDir {
+	case "", PositionFirst, PositionLast:
+	default:
+		return karma.Format(
+			nil,
+			"invalid position in dir value, should be %s or %s",
+			PositionFirst, PositionLast,
+		)
+	}
+
+	switch rule.SizeChanged {
+	case "", SizeChangeGrew, SizeChangeShrank, SizeChangeChanged:
+	default:
+		return karma.Format(
+			nil,
+			"invalid size changed value, should be %s, %s or %s",
+			SizeChangeGrew, SizeChangeShrank, SizeChangeChanged,
+		)
+	}
+
+	if rule.DirRecency != "" {
+		rule.dirRecency, err = time.ParseDuration(rule.DirRecency)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid dir recency value",
+			)
+		}
+
+		if rule.dirRecency <= 0 {
+			return errors.New("dir recency should be positive")
+		}
+	}
+
+	if rule.Generated != nil {
+		if len(rule.GeneratedPatterns) == 0 {
+			rule.GeneratedPatterns = defaultGeneratedPatterns
+		}
+
+		for _, pattern := range rule.GeneratedPatterns {
+			compiled, err := regexp.Compile(pattern)
 			if err != nil {
 				return karma.Format(
 					err,
-					"invalid depth value",
+					"invalid generated pattern",
 				)
 			}
+
+			rule.generatedPatterns = append(rule.generatedPatterns, compiled)
 		}
+	}
 
-		rule.depthValue = value
-		switch sign {
-		case '>', '<':
-			rule.depthComparison = sign
+	if rule.Keywords != "" {
+		rule.keywords, err = loadKeywords(os.ExpandEnv(rule.Keywords))
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to load keywords",
+			)
+		}
+	}
+
+	if rule.LicenseHeader != nil {
+		if rule.LicensePattern == "" {
+			rule.LicensePattern = defaultLicensePattern
+		}
+
+		rule.licensePattern, err = regexp.Compile(rule.LicensePattern)
+		if err != nil {
+			return karma.Format(
+				err,
+				"invalid license pattern",
+			)
+		}
+
+		if rule.LicenseLines == 0 {
+			rule.LicenseLines = defaultLicenseLines
 		}
 	}
 
@@ -72,41 +389,481 @@ func (rule *Rule) init() error {
 
 func (rule *Rule) Pass(file *File) bool {
 	if rule.Prefix != "" {
-		if !strings.HasPrefix(file.Path, rule.Prefix) {
+		if !strings.HasPrefix(file.MatchPath(), rule.Prefix) {
 			return false
 		}
 	}
 
 	if rule.Suffix != "" {
-		if !strings.HasSuffix(file.Path, rule.Suffix) {
+		if !strings.HasSuffix(file.MatchPath(), rule.Suffix) {
 			return false
 		}
 	}
 
-	if rule.depthValue != 0 {
-		depth := file.Depth()
+	if len(rule.except) > 0 {
+		path := filepath.ToSlash(filepath.Clean(file.MatchPath()))
+		for _, pattern := range rule.except {
+			if pattern.MatchPath(path) {
+				return false
+			}
+		}
+	}
+
+	if rule.AncestorDir != nil {
+		if !rule.AncestorDir.Match(file.MatchPath()) {
+			return false
+		}
+	}
+
+	if rule.Mode != "" {
+		if file.Mode.Perm()&rule.modeMask != rule.modeValue {
+			return false
+		}
+	}
+
+	if rule.Errored != nil {
+		if *rule.Errored != (file.Error != "") {
+			return false
+		}
+	}
 
-		switch rule.depthComparison {
-		case '<':
-			if depth >= rule.depthValue {
+	if rule.Uncommitted != nil {
+		if file.CommitTime == nil {
+			return false
+		}
+
+		if *rule.Uncommitted != file.ModTime.After(*file.CommitTime) {
+			return false
+		}
+	}
+
+	if rule.InBuildManifest != nil {
+		if *rule.InBuildManifest != isTrue(file.InBuildManifest) {
+			return false
+		}
+	}
+
+	if rule.SessionModified != nil {
+		if *rule.SessionModified != isTrue(file.SessionModified) {
+			return false
+		}
+	}
+
+	if rule.SizeChanged != "" {
+		if file.previousSize == nil {
+			return false
+		}
+
+		previous := *file.previousSize
+
+		switch rule.SizeChanged {
+		case SizeChangeGrew:
+			if file.Size <= previous {
 				return false
 			}
-		case '>':
-			if depth <= rule.depthValue {
+		case SizeChangeShrank:
+			if file.Size >= previous {
 				return false
 			}
-		default:
-			if depth != rule.depthValue {
+		case SizeChangeChanged:
+			if file.Size == previous {
 				return false
 			}
 		}
 	}
 
+	switch rule.PositionInDir {
+	case PositionFirst:
+		if !file.firstInDir {
+			return false
+		}
+	case PositionLast:
+		if !file.lastInDir {
+			return false
+		}
+	}
+
+	if rule.IsTest != nil {
+		if *rule.IsTest != rule.isTest(file.MatchPath()) {
+			return false
+		}
+	}
+
+	if rule.UppercaseRatio != "" {
+		ratio := getUppercaseRatio(filepath.Base(file.MatchPath()))
+		if !rule.uppercaseRatio.Match(ratio) {
+			return false
+		}
+	}
+
+	if rule.HasNumericSegment != nil {
+		if *rule.HasNumericSegment != rule.hasNumericSegment(file.MatchPath()) {
+			return false
+		}
+	}
+
+	if rule.DirRecency != "" {
+		if time.Since(file.dirModTime) >= rule.dirRecency {
+			return false
+		}
+	}
+
+	if rule.Depth != "" {
+		if !rule.depth.Match(float64(file.Depth())) {
+			return false
+		}
+	}
+
+	if rule.AccumulatedScore != "" {
+		if !rule.accumulatedScore.Match(file.accumulatedScore) {
+			return false
+		}
+	}
+
+	if rule.RepoDepth != "" {
+		if file.RepoDepth == nil || !rule.repoDepth.Match(float64(*file.RepoDepth)) {
+			return false
+		}
+	}
+
+	if rule.SizePercentile != "" {
+		if !rule.sizePercentile.Match(file.sizePercentile) {
+			return false
+		}
+	}
+
+	if rule.DirSizeRatio != "" {
+		if !rule.dirSizeRatio.Match(file.dirSizeRatio) {
+			return false
+		}
+	}
+
 	if rule.Binary != nil {
 		if *rule.Binary != file.Binary {
 			return false
 		}
 	}
 
+	if rule.GitIgnored != nil {
+		if *rule.GitIgnored != isTrue(file.GitIgnored) {
+			return false
+		}
+	}
+
+	if rule.Sparse != nil {
+		if *rule.Sparse != file.Sparse {
+			return false
+		}
+	}
+
+	if rule.MinLinks > 0 || rule.MaxLinks > 0 {
+		// link count is not known on every platform
+		if file.Links == 0 {
+			return false
+		}
+
+		if rule.MinLinks > 0 && file.Links < rule.MinLinks {
+			return false
+		}
+
+		if rule.MaxLinks > 0 && file.Links > rule.MaxLinks {
+			return false
+		}
+	}
+
+	if rule.GoParses != nil {
+		if !strings.HasSuffix(file.Path, ".go") {
+			return false
+		}
+
+		if *rule.GoParses != file.GoParses() {
+			return false
+		}
+	}
+
+	if rule.Owner != "" {
+		owned := false
+		for _, owner := range file.Owners {
+			if owner == rule.Owner {
+				owned = true
+				break
+			}
+		}
+
+		if !owned {
+			return false
+		}
+	}
+
+	if rule.linePattern != nil {
+		text := file.Text()
+		if text == nil {
+			return false
+		}
+
+		matched, total := countLines(text, rule.linePattern.Match)
+
+		if rule.LineCount != "" {
+			if !rule.lineCount.Match(float64(matched)) {
+				return false
+			}
+		}
+
+		if rule.LineRatio != "" {
+			if total == 0 {
+				return false
+			}
+
+			if !rule.lineRatio.Match(float64(matched) / float64(total)) {
+				return false
+			}
+		}
+	}
+
+	if rule.LicenseHeader != nil {
+		text := file.Text()
+		if text == nil {
+			return false
+		}
+
+		head := getHeadLines(text, rule.LicenseLines)
+		if *rule.LicenseHeader != rule.licensePattern.Match(head) {
+			return false
+		}
+	}
+
+	if rule.LineEnding != "" {
+		if file.GetLineEnding() != rule.LineEnding {
+			return false
+		}
+	}
+
+	if rule.NonPrintableRatio != "" {
+		ratio, ok := file.GetNonPrintableRatio()
+		if !ok {
+			return false
+		}
+
+		if !rule.nonPrintableRatio.Match(ratio) {
+			return false
+		}
+	}
+
+	if rule.Generated != nil {
+		text := file.Text()
+		if text == nil {
+			return false
+		}
+
+		head := getHeadLines(text, defaultGeneratedLines)
+
+		generated := false
+		for _, pattern := range rule.generatedPatterns {
+			if pattern.Match(head) {
+				generated = true
+				break
+			}
+		}
+
+		if *rule.Generated != generated {
+			return false
+		}
+	}
+
+	if rule.IsContentDuplicate != nil {
+		if *rule.IsContentDuplicate != file.contentDuplicate {
+			return false
+		}
+	}
+
+	if rule.HasConflictMarkers != nil {
+		text := file.Text()
+		if text == nil {
+			return false
+		}
+
+		if *rule.HasConflictMarkers != hasConflictMarkers(text) {
+			return false
+		}
+	}
+
+	if len(rule.ContainsAll) > 0 {
+		if !containsAll(file.Text(), rule.ContainsAll) {
+			return false
+		}
+	}
+
+	if rule.Keywords != "" {
+		if !containsKeyword([]byte(file.MatchPath()), rule.keywords) &&
+			!containsKeyword(file.Text(), rule.keywords) {
+			return false
+		}
+	}
+
 	return true
 }
+
+// Match reports whether ancestor directory of given path at configured level
+// exists and its name matches pattern.
+func (ancestor *AncestorDir) Match(path string) bool {
+	components := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+
+	index := len(components) - 1 - ancestor.Level
+	if index < 0 {
+		return false
+	}
+
+	return matchGlob(ancestor.Pattern, components[index])
+}
+
+// Delta returns score which should be added to given file's score if it
+// passes the rule.
+func (rule *Rule) Delta(file *File) int {
+	delta := rule.Score
+
+	if rule.DirRecency != "" {
+		age := time.Since(file.dirModTime)
+		if age < 0 {
+			age = 0
+		}
+
+		delta = int(float64(delta) * (1 - float64(age)/float64(rule.dirRecency)))
+	}
+
+	if rule.NearHere {
+		delta = delta / (1 + file.hereDistance)
+	}
+
+	if len(rule.Buckets) > 0 {
+		delta += rule.Buckets[rule.getBucket(file.MatchPath())]
+	}
+
+	if rule.ScoreMin != nil && delta < *rule.ScoreMin {
+		delta = *rule.ScoreMin
+	}
+
+	if rule.ScoreMax != nil && delta > *rule.ScoreMax {
+		delta = *rule.ScoreMax
+	}
+
+	return delta
+}
+
+// isTest reports whether path looks like a test file, either by its suffix
+// or by living in a test directory.
+func (rule *Rule) isTest(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	for _, suffix := range rule.TestSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+
+	components := strings.Split(path, "/")
+	for _, dir := range components[:len(components)-1] {
+		for _, testDir := range rule.TestDirs {
+			if dir == testDir {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasNumericSegment reports whether any component of path, ignoring file's
+// extension, is a number like 2024 or v1 within rule's numeric bounds.
+func (rule *Rule) hasNumericSegment(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	path = strings.TrimSuffix(path, filepath.Ext(path))
+
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimPrefix(segment, "v")
+		if segment == "" || strings.Trim(segment, "0123456789") != "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(segment)
+		if err != nil {
+			continue
+		}
+
+		if rule.NumericMin != nil && value < *rule.NumericMin {
+			continue
+		}
+
+		if rule.NumericMax != nil && value > *rule.NumericMax {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// getUppercaseRatio returns fraction of uppercase letters among all letters
+// of given name, names without letters yield zero.
+func getUppercaseRatio(name string) float64 {
+	var letters, uppercase int
+
+	for _, char := range name {
+		if !unicode.IsLetter(char) {
+			continue
+		}
+
+		letters++
+
+		if unicode.IsUpper(char) {
+			uppercase++
+		}
+	}
+
+	if letters == 0 {
+		return 0
+	}
+
+	return float64(uppercase) / float64(letters)
+}
+
+// getBucket deterministically maps path to one of rule's buckets, mapping
+// depends only on path and rule's seed.
+func (rule *Rule) getBucket(path string) int {
+	seed := make([]byte, 8)
+	binary.LittleEndian.PutUint64(seed, rule.Seed)
+
+	hash := fnv.New64a()
+	hash.Write(seed)
+	hash.Write([]byte(path))
+
+	return int(hash.Sum64() % uint64(len(rule.Buckets)))
+}
+
+// readsContent reports whether rule needs to look into file contents.
+func (rule Rule) readsContent() bool {
+	return rule.LinePattern != "" ||
+		rule.LicenseHeader != nil ||
+		rule.Keywords != "" ||
+		len(rule.ContainsAll) > 0 ||
+		rule.Generated != nil ||
+		rule.HasConflictMarkers != nil ||
+		rule.IsContentDuplicate != nil ||
+		rule.NonPrintableRatio != "" ||
+		rule.LineEnding != ""
+}
+
+func hasRule(rules []Rule, match func(Rule) bool) bool {
+	for _, rule := range rules {
+		if match(rule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTrue reports whether lazily calculated file property is set and true.
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRuleScoreBounds(t *testing.T) {
+	rules := []Rule{
+		newRule(t, Rule{Suffix: ".go", Score: 100, ScoreMax: intPtr(30)}),
+		newRule(t, Rule{Suffix: ".md", Score: -100, ScoreMin: intPtr(-5)}),
+		newRule(t, Rule{
+			Suffix:   ".txt",
+			Score:    1,
+			Buckets:  []int{50, 50},
+			ScoreMin: intPtr(0),
+			ScoreMax: intPtr(10),
+		}),
+		newRule(t, Rule{Suffix: ".go", Score: 2, ScoreMax: intPtr(30)}),
+	}
+
+	files := applyRules(
+		context.Background(),
+		[]*File{
+			{Path: "main.go"},
+			{Path: "README.md"},
+			{Path: "notes.txt"},
+		},
+		rules,
+		nil,
+	)
+
+	for path, score := range map[string]int{
+		"main.go":   32,
+		"README.md": -5,
+		"notes.txt": 10,
+	} {
+		file := findFile(files, path)
+		if file.Score != score {
+			t.Errorf("%s: score = %d, want %d", path, file.Score, score)
+		}
+	}
+}
+
+func TestRuleBuckets(t *testing.T) {
+	rule := newRule(t, Rule{Score: 0, Buckets: []int{0, 10, 20, 30}, Seed: 1})
+	same := newRule(t, Rule{Score: 0, Buckets: []int{0, 10, 20, 30}, Seed: 1})
+	seeded := newRule(t, Rule{Score: 0, Buckets: []int{0, 10, 20, 30}, Seed: 2})
+
+	differs := false
+	used := map[int]bool{}
+
+	for i := 0; i < 100; i++ {
+		file := &File{Path: "dir/file" + string(rune('a'+i%26)) + string(rune('0'+i/26))}
+
+		bucket := rule.getBucket(file.Path)
+		used[bucket] = true
+
+		if rule.getBucket(file.Path) != bucket || same.getBucket(file.Path) != bucket {
+			t.Fatalf("%s: same path should land in the same bucket", file.Path)
+		}
+
+		if rule.Delta(file) != rule.Buckets[bucket] {
+			t.Fatalf("%s: bucket's score should be added", file.Path)
+		}
+
+		if seeded.getBucket(file.Path) != bucket {
+			differs = true
+		}
+	}
+
+	if !differs {
+		t.Errorf("changing seed should change assignment of some paths")
+	}
+
+	if len(used) != len(rule.Buckets) {
+		t.Errorf("paths should be spread over all buckets, used %d", len(used))
+	}
+}
+
+func TestRuleDepth(t *testing.T) {
+	for depth, expected := range map[string][]bool{
+		"1":  {true, false, false},
+		"<3": {true, true, false},
+		">1": {false, true, true},
+		"0":  {false, false, false},
+	} {
+		rule := newRule(t, Rule{Depth: depth, Score: 1})
+
+		for i, path := range []string{"a.go", "a/b.go", "a/b/c.go"} {
+			if rule.Pass(&File{Path: path}) != expected[i] {
+				t.Errorf("depth %s: %s passed = %v", depth, path, !expected[i])
+			}
+		}
+	}
+}
+
+func TestRuleExcept(t *testing.T) {
+	sources := newRule(t, Rule{Suffix: ".go", Except: "*_test.go", Score: 1})
+	commands := newRule(t, Rule{Prefix: "cmd/", Except: "cmd/legacy/**", Score: 1})
+
+	for path, expected := range map[string][]bool{
+		"main.go":                {true, false},
+		"main_test.go":           {false, false},
+		"pkg/util_test.go":       {false, false},
+		"cmd/tool/main.go":       {true, true},
+		"cmd/legacy/old/main.go": {true, false},
+		"cmd/legacy.go":          {true, true},
+		"README.md":              {false, false},
+	} {
+		for i, rule := range []Rule{sources, commands} {
+			if rule.Pass(&File{Path: path}) != expected[i] {
+				t.Errorf("%s: rule %s passed = %v", path, rule, !expected[i])
+			}
+		}
+	}
+
+	rule := Rule{Suffix: ".go", Except: "{a,b", Score: 1}
+	if rule.init() == nil {
+		t.Errorf("invalid except pattern should be reported")
+	}
+}
+
+func TestRuleHasNumericSegment(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		path    string
+		matches bool
+	}{
+		{Rule{}, "api/v1/handler.go", true},
+		{Rule{}, "reports/2024/summary.md", true},
+		{Rule{}, "migrations/001.sql", true},
+		{Rule{}, "src/main.go", false},
+		{Rule{}, "v8engine/run.go", false},
+		{Rule{}, "docs/v/index.md", false},
+		{Rule{NumericMin: intPtr(2023)}, "reports/2024/summary.md", true},
+		{Rule{NumericMin: intPtr(2023)}, "reports/2019/summary.md", false},
+		{Rule{NumericMax: intPtr(2)}, "api/v1/handler.go", true},
+		{Rule{NumericMax: intPtr(2)}, "api/v3/handler.go", false},
+		{
+			Rule{NumericMin: intPtr(2), NumericMax: intPtr(3)},
+			"api/v1/2/handler.go", true,
+		},
+	}
+
+	for _, test := range tests {
+		test.rule.HasNumericSegment = boolPtr(true)
+
+		rule := newRule(t, test.rule)
+
+		if matches := rule.Pass(&File{Path: test.path}); matches != test.matches {
+			t.Errorf("%s: %s: expected match %v", rule, test.path, test.matches)
+		}
+	}
+
+	rule := newRule(t, Rule{HasNumericSegment: boolPtr(false)})
+	if !rule.Pass(&File{Path: "src/main.go"}) {
+		t.Errorf("file without numeric segments should match negated rule")
+	}
+}
+
+func TestRuleIsTest(t *testing.T) {
+	rule := newRule(t, Rule{IsTest: boolPtr(true)})
+
+	tests := map[string]bool{
+		"main_test.go":                 true,
+		"web/app.test.js":              true,
+		"web/app.spec.ts":              true,
+		"test/fixtures/data.json":      true,
+		"pkg/tests/helpers.py":         true,
+		"web/__tests__/button.jsx":     true,
+		"main.go":                      false,
+		"web/app.js":                   false,
+		"docs/testing.md":              false,
+		"contest/solution.go":          false,
+		"tools/test":                   false,
+		"internal/latest_tests_doc.md": false,
+	}
+
+	for path, expected := range tests {
+		if rule.Pass(&File{Path: path}) != expected {
+			t.Errorf("%s: expected test file %v", path, expected)
+		}
+	}
+
+	custom := newRule(t, Rule{
+		IsTest:       boolPtr(true),
+		TestSuffixes: []string{"Test.java"},
+		TestDirs:     []string{"qa"},
+	})
+
+	for path, expected := range map[string]bool{
+		"src/FooTest.java":  true,
+		"qa/smoke.sh":       true,
+		"main_test.go":      false,
+		"test/fixture.json": false,
+	} {
+		if custom.Pass(&File{Path: path}) != expected {
+			t.Errorf("%s: custom conventions: expected test file %v", path, expected)
+		}
+	}
+}
+
+func TestRuleAncestorDir(t *testing.T) {
+	grandparent := newRule(t, Rule{
+		AncestorDir: &AncestorDir{Level: 2, Pattern: "services"},
+	})
+
+	parent := newRule(t, Rule{
+		AncestorDir: &AncestorDir{Pattern: "api*"},
+	})
+
+	tests := []struct {
+		path        string
+		grandparent bool
+		parent      bool
+	}{
+		{"services/billing/main.go", true, false},
+		{"services/main.go", false, false},
+		{"cmd/services/main.go", false, false},
+		{"services/services/main.go", true, false},
+		{"services/api/handler.go", true, true},
+		{"apigw/main.go", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, test := range tests {
+		file := &File{Path: test.path}
+
+		if grandparent.Pass(file) != test.grandparent {
+			t.Errorf("%s: grandparent: expected %v", test.path, test.grandparent)
+		}
+
+		if parent.Pass(file) != test.parent {
+			t.Errorf("%s: parent: expected %v", test.path, test.parent)
+		}
+	}
+
+	for _, ancestor := range []AncestorDir{
+		{Level: -1, Pattern: "x"},
+		{Level: 1, Pattern: ""},
+		{Level: 1, Pattern: "["},
+	} {
+		rule := Rule{AncestorDir: &ancestor}
+		if rule.init() == nil {
+			t.Errorf("%+v: expected error", ancestor)
+		}
+	}
+}
+
+func TestRuleUppercaseRatio(t *testing.T) {
+	capitals := newRule(t, Rule{UppercaseRatio: ">0.5"})
+	mixed := newRule(t, Rule{UppercaseRatio: ">0"})
+	lowercase := newRule(t, Rule{UppercaseRatio: "0"})
+
+	tests := []struct {
+		path      string
+		capitals  bool
+		mixed     bool
+		lowercase bool
+	}{
+		{"README.md", true, true, false},
+		{"src/my_file.go", false, false, true},
+		{"docs/MixedCase.txt", false, true, false},
+		{"LICENSE", true, true, false},
+		{"Docs/lower.txt", false, false, true},
+		{"2024.csv", false, false, true},
+	}
+
+	for _, test := range tests {
+		file := &File{Path: test.path}
+
+		if capitals.Pass(file) != test.capitals {
+			t.Errorf("%s: >0.5: expected %v", test.path, test.capitals)
+		}
+
+		if mixed.Pass(file) != test.mixed {
+			t.Errorf("%s: >0: expected %v", test.path, test.mixed)
+		}
+
+		if lowercase.Pass(file) != test.lowercase {
+			t.Errorf("%s: 0: expected %v", test.path, test.lowercase)
+		}
+	}
+
+	if ratio := getUppercaseRatio("README.md"); ratio != 0.75 {
+		t.Errorf("README.md: expected ratio 0.75, got %v", ratio)
+	}
+}
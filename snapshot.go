@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	SizeChangeGrew    = "grew"
+	SizeChangeShrank  = "shrank"
+	SizeChangeChanged = "changed"
+)
+
+// getSnapshotPath returns path of file with sizes of files seen during
+// previous run in current directory.
+func getSnapshotPath() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", karma.Format(
+			err,
+			"unable to find cache directory",
+		)
+	}
+
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return "", karma.Format(
+			err,
+			"unable to resolve root directory",
+		)
+	}
+
+	sum := sha256.Sum256([]byte(root))
+
+	return filepath.Join(
+		cache, "prols", "sizes-"+hex.EncodeToString(sum[:8])+".json",
+	), nil
+}
+
+// markPreviousSizes marks files with sizes recorded by previous run, the
+// snapshot itself is left untouched.
+func markPreviousSizes(files []*File) error {
+	path, err := getSnapshotPath()
+	if err != nil {
+		return err
+	}
+
+	previous := map[string]int64{}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		err = json.Unmarshal(data, &previous)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to decode %s", path,
+			)
+		}
+
+	case !os.IsNotExist(err):
+		return karma.Format(
+			err,
+			"unable to read %s", path,
+		)
+	}
+
+	for _, file := range files {
+		if size, ok := previous[filepath.Clean(file.Path)]; ok {
+			file.previousSize = &size
+		}
+	}
+
+	return nil
+}
+
+// saveSizes replaces snapshot of previous run with current sizes of files.
+func saveSizes(files []*File) error {
+	path, err := getSnapshotPath()
+	if err != nil {
+		return err
+	}
+
+	current := map[string]int64{}
+	for _, file := range files {
+		current[filepath.Clean(file.Path)] = file.Size
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to encode sizes",
+		)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to create directory for %s", path,
+		)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to write %s", path,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSizeChanged(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	chdirTemp(t, map[string]string{
+		"app.log":  "started\n",
+		"data.csv": "a,b\n1,2\n",
+		"same.txt": "same\n",
+	})
+
+	grew := newRule(t, Rule{SizeChanged: SizeChangeGrew})
+	shrank := newRule(t, Rule{SizeChanged: SizeChangeShrank})
+	changed := newRule(t, Rule{SizeChanged: SizeChangeChanged})
+
+	run := func() []*File {
+		files := walkTemp(t, &Config{})
+
+		err := markPreviousSizes(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = saveSizes(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return files
+	}
+
+	for _, file := range run() {
+		if changed.Pass(file) {
+			t.Errorf("%s: should not match on first run", file.Path)
+		}
+	}
+
+	writeFile(t, "app.log", "started\nrunning\n")
+	writeFile(t, "data.csv", "a,b\n")
+
+	files := run()
+
+	tests := []struct {
+		path    string
+		grew    bool
+		shrank  bool
+		changed bool
+	}{
+		{"app.log", true, false, true},
+		{"data.csv", false, true, true},
+		{"same.txt", false, false, false},
+	}
+
+	for _, test := range tests {
+		file := findFile(files, test.path)
+
+		if grew.Pass(file) != test.grew {
+			t.Errorf("%s: grew: expected %v", test.path, test.grew)
+		}
+
+		if shrank.Pass(file) != test.shrank {
+			t.Errorf("%s: shrank: expected %v", test.path, test.shrank)
+		}
+
+		if changed.Pass(file) != test.changed {
+			t.Errorf("%s: changed: expected %v", test.path, test.changed)
+		}
+	}
+}
+
+func TestSizeChangedDeadline(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	chdirTemp(t, map[string]string{
+		"a.log": "",
+		"b.log": "",
+	})
+
+	config := filepath.Join(t.TempDir(), "prols.json")
+	writeFile(t, config, `{
+    "ignore_dirs": [".git"],
+    "lister": ["sh", "-c", "exec 2>/dev/null; echo a.log; sleep 5; echo b.log"],
+    "rules": [{"size_changed": "changed", "score": 10}]
+}`)
+
+	_, stderr, err := runProls(t, "-c", config, "--deadline", "200ms")
+	if err != nil {
+		t.Fatalf("%s: %s", err, stderr)
+	}
+
+	path, err := getSnapshotPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(path)
+	if !os.IsNotExist(err) {
+		t.Errorf("sizes snapshot should not be saved after deadline: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+)
+
+func isSparse(info os.FileInfo) bool {
+	return false
+}
+
+func getLinks(info os.FileInfo) int {
+	return 0
+}
@@ -0,0 +1,79 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSparse(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"dense.txt": "contents\n",
+	})
+
+	sparse, err := os.Create("sparse.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sparse.Truncate(16 * 1024 * 1024)
+	sparse.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := walkTemp(t, &Config{})
+
+	if !findFile(files, "sparse.img").Sparse {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+
+	rule := newRule(t, Rule{Sparse: boolPtr(true), Score: 1})
+
+	if !rule.Pass(findFile(files, "sparse.img")) {
+		t.Errorf("sparse file should match")
+	}
+
+	if rule.Pass(findFile(files, "dense.txt")) {
+		t.Errorf("dense file should not match")
+	}
+}
+
+func TestLinks(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"original.txt": "contents\n",
+		"single.txt":   "contents\n",
+	})
+
+	err := os.Link("original.txt", "link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := walkTemp(t, &Config{})
+
+	shared := newRule(t, Rule{MinLinks: 2, Score: 1})
+	single := newRule(t, Rule{MaxLinks: 1, Score: 1})
+
+	for path, linked := range map[string]bool{
+		"original.txt": true,
+		"link.txt":     true,
+		"single.txt":   false,
+	} {
+		file := findFile(files, path)
+
+		if shared.Pass(file) != linked {
+			t.Errorf("%s: min_links: 2 passed = %v", path, !linked)
+		}
+
+		if single.Pass(file) == linked {
+			t.Errorf("%s: max_links: 1 passed = %v", path, linked)
+		}
+	}
+
+	unknown := &File{Path: "merged.txt"}
+	if shared.Pass(unknown) || single.Pass(unknown) {
+		t.Errorf("files with unknown number of links should never pass")
+	}
+}